@@ -9,8 +9,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/shamil/proxy_track_service-1/internal/client"
 	"github.com/shamil/proxy_track_service-1/internal/client/fourpx"
 	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/handler"
+	"github.com/shamil/proxy_track_service-1/internal/proxypool"
+	"github.com/shamil/proxy_track_service-1/internal/ratelimit"
 	"github.com/shamil/proxy_track_service-1/internal/repository"
 	"github.com/shamil/proxy_track_service-1/internal/server"
 	"github.com/shamil/proxy_track_service-1/internal/service"
@@ -33,21 +37,108 @@ func RunApp() {
 	}
 	defer cache.Close()
 
-	externalClient := fourpx.NewFourPXClient(cfg.External.BaseURL, cfg.External.HashPattern, cfg.External.Timeout)
+	proxyPool, err := proxypool.NewPool(cfg.Proxy)
+	if err != nil {
+		log.Fatalf("Failed to initialize proxy pool: %v", err)
+	}
+	if proxyPool.Size() > 0 {
+		log.Printf("Outbound proxy pool: %d proxies", proxyPool.Size())
+	}
+
+	var snapshots repository.SnapshotRepository
+	switch cfg.Snapshot.Store {
+	case "":
+	case config.SnapshotStoreFile:
+		snapshots, err = repository.NewFileSnapshotStore(cfg.Snapshot.Dir, cfg.Snapshot.MaxCount, cfg.Snapshot.Retention)
+	case config.SnapshotStoreRedis:
+		snapshots, err = repository.NewRedisSnapshotStore(cfg.Redis, cfg.Snapshot.MaxCount, cfg.Snapshot.Retention)
+	default:
+		log.Fatalf("Unknown snapshot store: %s", cfg.Snapshot.Store)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize snapshot store: %v", err)
+	}
+
+	var history repository.HistoryRepository
+	if cfg.History.Driver != "" {
+		history, err = repository.NewSQLHistoryStore(cfg.History)
+		if err != nil {
+			log.Fatalf("Failed to initialize history store: %v", err)
+		}
+		defer history.Close()
+		log.Printf("Tracking history store: %s", cfg.History.Driver)
+	}
+
+	var changes repository.ChangeRepository
+	if cfg.Changes.Enabled {
+		changes, err = repository.NewRedisChangeStore(cfg.Redis, cfg.Changes.Retention, cfg.Changes.MaxPerCode)
+		if err != nil {
+			log.Fatalf("Failed to initialize change store: %v", err)
+		}
+		defer changes.Close()
+	}
+
+	var watches repository.WatchRepository
+	if cfg.Scheduler.Enabled {
+		watches, err = repository.NewRedisWatchStore(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to initialize watch store: %v", err)
+		}
+		defer watches.Close()
+	}
+
+	var pending repository.PendingQueueRepository
+	if cfg.Batcher.DurableQueue {
+		pending, err = repository.NewRedisPendingQueue(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to initialize pending queue: %v", err)
+		}
+		defer pending.Close()
+	}
+
+	var pageGate client.PageGate
+	if cfg.RateLimit.Enabled {
+		rateLimits, err := repository.NewRedisRateLimitStore(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to initialize rate limit store: %v", err)
+		}
+		defer rateLimits.Close()
+
+		pageGate = ratelimit.New(cfg.RateLimit, rateLimits).Gate(fourpx.ProviderName)
+		limit := cfg.RateLimit.Limit(fourpx.ProviderName)
+		log.Printf("Provider rate limit: %.0f pages/min, %d sessions", limit.PagesPerMinute, limit.MaxSessions)
+	}
+
+	var externalClient client.ExternalAPIClient
+	switch cfg.External.Mode {
+	case config.ExternalModeHTTP:
+		externalClient = fourpx.NewFourPXHTTPClient(cfg.External, proxyPool, snapshots)
+	case config.ExternalModeBrowser:
+		externalClient = fourpx.NewFourPXClient(cfg.External, proxyPool, snapshots, cfg.Snapshot.Screenshot)
+	default:
+		log.Fatalf("Unknown external API mode: %s", cfg.External.Mode)
+	}
+	log.Printf("External API client mode: %s", cfg.External.Mode)
+
+	switch cfg.Batcher.OverflowPolicy {
+	case config.OverflowBlock, config.OverflowRejectOldest, config.OverflowRejectNewest, config.OverflowMerge:
+	default:
+		log.Fatalf("Unknown batch overflow policy: %s", cfg.Batcher.OverflowPolicy)
+	}
 
 	serviceConfig := service.ServiceConfig{
-		BatcherConfig: cfg.Batcher,
-		ClientConfig:  cfg.External,
+		BatcherConfig:   cfg.Batcher,
+		ClientConfig:    cfg.External,
+		SchedulerConfig: cfg.Scheduler,
 	}
 
-	trackingService := service.NewTrackingService(serviceConfig, cache, externalClient)
+	trackingService := service.NewTrackingService(serviceConfig, cache, externalClient, history, changes, watches, pending, pageGate)
 
 	if err := trackingService.Start(ctx); err != nil {
 		log.Fatalf("Failed to start tracking service: %v", err)
 	}
-	defer trackingService.Stop()
 
-	router := server.SetupRoutes(trackingService)
+	router := server.SetupRoutes(trackingService, handler.NewAdminHandler(proxyPool, snapshots), cfg.Server.AdminToken)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -72,9 +163,27 @@ func RunApp() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
+	// Shutdown closes the listeners at once and then waits for handlers,
+	// which in turn wait for the batches drained below.
+	serverDone := make(chan error, 1)
+	go func() {
+		serverDone <- srv.Shutdown(shutdownCtx)
+	}()
+
+	drainCtx, drainCancel := context.WithTimeout(shutdownCtx, cfg.Batcher.DrainTimeout)
+	defer drainCancel()
+
+	if err := trackingService.Shutdown(drainCtx); err != nil {
+		log.Printf("Tracking service shutdown failed: %v", err)
+	}
+
+	if err := <-serverDone; err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	if err := externalClient.Close(); err != nil {
+		log.Printf("Failed to close external API client: %v", err)
+	}
+
 	log.Println("Server exited")
 }
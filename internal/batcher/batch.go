@@ -2,338 +2,264 @@ package batcher
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
 	"time"
 
-	"errors"
-
+	"github.com/shamil/proxy_track_service-1/internal/batching"
 	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/clock"
 	"github.com/shamil/proxy_track_service-1/internal/config"
 	"github.com/shamil/proxy_track_service-1/internal/erors"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 	"github.com/shamil/proxy_track_service-1/internal/repository"
 )
 
+// Batcher batches track codes into provider lookups. Queueing, flushing and
+// draining are done by a batching.Batcher; this type adds the cache, the
+// result hooks, the pending queue and the caller-facing messages.
 type Batcher struct {
 	config config.BatcherConfig
 	cache  repository.CacheRepository
 	client client.ExternalAPIClient
+	engine *batching.Batcher[string, *models.TrackData]
 
-	mu          sync.Mutex
-	batch       []batchItem
-	batchTimer  *time.Timer
-	inputChan   chan batchRequest
-	workerChan  chan []batchItem
-	flushSignal chan struct{}
-	stopChan    chan struct{}
+	resultHooks  []ResultHook
+	pendingQueue repository.PendingQueueRepository
+	pageGate     client.PageGate
+	clock        clock.Clock
 }
 
-func NewBatcher(config config.BatcherConfig, cache repository.CacheRepository, client client.ExternalAPIClient) BatcherInterface {
-	b := &Batcher{
-		config:      config,
-		cache:       cache,
-		client:      client,
-		inputChan:   make(chan batchRequest, config.BatchSize*2),
-		workerChan:  make(chan []batchItem, config.Workers),
-		flushSignal: make(chan struct{}, 1),
-		stopChan:    make(chan struct{}),
-		batch:       make([]batchItem, 0, config.BatchSize),
-	}
+// defaultCacheTTL applies when the config leaves CacheTTL unset; a zero TTL
+// would keep entries in Redis forever.
+const defaultCacheTTL = 5 * time.Minute
 
-	b.batchTimer = time.NewTimer(0)
-	if !b.batchTimer.Stop() {
-		<-b.batchTimer.C
+func NewBatcher(config config.BatcherConfig, cache repository.CacheRepository, client client.ExternalAPIClient, opts ...Option) BatcherInterface {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultCacheTTL
 	}
 
-	return b
-}
-
-func (b *Batcher) AddRequest(ctx context.Context, trackCode string) <-chan models.TrackResponse {
-	respChan := make(chan models.TrackResponse, 1)
-
-	select {
-	case b.inputChan <- batchRequest{
-		ctx:         ctx,
-		trackCode:   trackCode,
-		respChannel: respChan,
-	}:
-		return respChan
-
-	case <-ctx.Done():
-		respChan <- models.TrackResponse{
-			Status: false,
-			Error:  "request cancelled",
-		}
-		return respChan
-
-	default:
-		respChan <- models.TrackResponse{
-			Status: false,
-			Error:  "service busy, try again later",
-		}
-		return respChan
+	b := &Batcher{
+		config: config,
+		cache:  cache,
+		client: client,
 	}
-}
 
-func (b *Batcher) Start(ctx context.Context) error {
-	for i := 0; i < b.config.Workers; i++ {
-		go b.worker(ctx)
+	for _, opt := range opts {
+		opt(b)
 	}
 
-	go b.timerManager(ctx)
-
-	go b.mainLoop(ctx)
+	var engineOpts []batching.Option[string, *models.TrackData]
+	if b.pendingQueue != nil {
+		engineOpts = append(engineOpts, batching.WithLeftovers[string, *models.TrackData](b.persist))
+	}
+	engineCfg := engineConfig(config)
+	engineCfg.Clock = b.clock
+	b.engine = batching.New(engineCfg, b.track, engineOpts...)
 
-	return nil
+	return b
 }
 
-func (b *Batcher) mainLoop(ctx context.Context) {
-	for {
-		select {
-		case req := <-b.inputChan:
-			b.addToBatch(req)
-
-		case <-b.flushSignal:
-			b.mu.Lock()
-			if len(b.batch) > 0 {
-				b.flushBatchLocked()
-			}
-			b.mu.Unlock()
-
-		case <-ctx.Done():
-			b.mu.Lock()
-			if len(b.batch) > 0 {
-				b.flushBatchLocked()
-			}
-			b.mu.Unlock()
-			return
+func engineConfig(cfg config.BatcherConfig) batching.Config {
+	batcherMetrics := &batching.Metrics{
+		Rejected:     metrics.BatcherRejected,
+		Overflow:     metrics.BatcherOverflow,
+		Cancelled:    metrics.BatcherCancelled,
+		BatchSize:    metrics.BatcherBatchSize,
+		FlushTimeout: metrics.BatcherFlushTimeout,
+		Latency:      metrics.BatcherScrapeLatency,
+		ErrorRate:    metrics.BatcherErrorRate,
+	}
 
-		case <-b.stopChan:
-			return
-		}
+	var policy batching.FlushPolicy
+	if cfg.Adaptive {
+		policy = batching.NewAdaptivePolicy(batching.AdaptiveConfig{
+			Size:          cfg.BatchSize,
+			Timeout:       cfg.BatchTimeout,
+			MinSize:       cfg.MinBatchSize,
+			MaxSize:       cfg.MaxBatchSize,
+			MinTimeout:    cfg.MinBatchTimeout,
+			MaxTimeout:    cfg.MaxBatchTimeout,
+			TargetLatency: cfg.TargetLatency,
+			MaxErrorRate:  cfg.MaxErrorRate,
+		}, batcherMetrics)
 	}
-}
 
-func (b *Batcher) timerManager(ctx context.Context) {
-	for {
-		b.mu.Lock()
-		hasItems := len(b.batch) > 0
-		b.mu.Unlock()
-
-		if hasItems {
-			b.batchTimer.Reset(b.config.BatchTimeout)
-
-			select {
-			case <-b.batchTimer.C:
-				select {
-				case b.flushSignal <- struct{}{}:
-				default:
-				}
-			case <-ctx.Done():
-				return
-			case <-b.stopChan:
-				return
-			}
-		} else {
-			select {
-			case <-time.After(100 * time.Millisecond):
-			case <-ctx.Done():
-				return
-			case <-b.stopChan:
-				return
-			}
-		}
+	return batching.Config{
+		BatchSize:          cfg.BatchSize,
+		BatchTimeout:       cfg.BatchTimeout,
+		Policy:             policy,
+		Workers:            cfg.Workers,
+		InteractiveTimeout: cfg.InteractiveTimeout,
+		StarvationTimeout:  cfg.StarvationTimeout,
+		InteractiveWeight:  cfg.InteractiveWeight,
+		NormalWeight:       cfg.NormalWeight,
+		BulkWeight:         cfg.BulkWeight,
+		QueueCapacity:      cfg.QueueCapacity,
+		EnqueueTimeout:     cfg.EnqueueTimeout,
+		RetryAfter:         cfg.RetryAfter,
+		Overflow:           batching.OverflowPolicy(cfg.OverflowPolicy),
+		BatchDeadline:      cfg.BatchDeadline,
+		Metrics:            batcherMetrics,
 	}
 }
 
-func (b *Batcher) Stop() error {
-	close(b.stopChan)
-
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// AddRequest queues trackCode in the lane of priority. When the lane is full
+// the caller waits up to EnqueueTimeout for room before it is answered with
+// a Retry-After hint.
+func (b *Batcher) AddRequest(ctx context.Context, trackCode string, priority Priority) <-chan models.TrackResponse {
+	respChan := make(chan models.TrackResponse, 1)
+	b.engine.SubmitFunc(ctx, trackCode, priority, func(result batching.Result[*models.TrackData]) {
+		respChan <- trackResponse(result)
+	})
+	return respChan
+}
 
-	if !b.batchTimer.Stop() {
-		select {
-		case <-b.batchTimer.C:
-		default:
-		}
+func (b *Batcher) Start(ctx context.Context) error {
+	if err := b.engine.Start(ctx); err != nil {
+		return err
 	}
 
-	for _, item := range b.batch {
-		select {
-		case item.responseChan <- models.TrackResponse{
-			Status: false,
-			Error:  "service shutting down",
-		}:
-		default:
-		}
+	if b.pendingQueue != nil {
+		go b.recoverPending(ctx)
 	}
-	b.batch = b.batch[:0]
 
 	return nil
 }
 
-func (b *Batcher) Health(ctx context.Context) error {
-	select {
-	case b.inputChan <- batchRequest{}:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(100 * time.Millisecond):
-		return errors.New("batcher input channel blocked")
-	}
+// Stop fails every pending request right away; Shutdown drains them first.
+func (b *Batcher) Stop() error {
+	return b.engine.Stop()
 }
 
-func (b *Batcher) Flush() {
-	select {
-	case b.flushSignal <- struct{}{}:
-	default:
-	}
+// Shutdown stops accepting requests, sends everything pending to the
+// provider and waits for it until ctx is done. Requests left over are saved
+// to the pending queue, if configured.
+func (b *Batcher) Shutdown(ctx context.Context) error {
+	err := b.engine.Shutdown(ctx)
+	log.Println("batcher.Shutdown.Drained")
+	return err
 }
 
-func (b *Batcher) addToBatch(req batchRequest) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if req.ctx != nil {
-		select {
-		case <-req.ctx.Done():
-			req.respChannel <- models.TrackResponse{
-				Status: false,
-				Error:  "request cancelled",
-			}
-			return
-		default:
-		}
-	}
-
-	if len(b.batch) == 0 {
-		b.batchTimer.Reset(b.config.BatchTimeout)
-	}
-
-	b.batch = append(b.batch, batchItem{
-		trackCode:    req.trackCode,
-		responseChan: req.respChannel,
-	})
-
-	if len(b.batch) >= b.config.BatchSize {
-		b.flushBatchLocked()
-	}
+func (b *Batcher) Health(ctx context.Context) error {
+	return b.engine.Health(ctx)
 }
 
-func (b *Batcher) flushBatchLocked() {
-	if len(b.batch) == 0 {
-		return
-	}
-
-	batchToSend := make([]batchItem, len(b.batch))
-	copy(batchToSend, b.batch)
-	b.batch = b.batch[:0]
-
-	if !b.batchTimer.Stop() {
-		select {
-		case <-b.batchTimer.C:
-		default:
-		}
-	}
+func (b *Batcher) Stats() Stats {
+	return b.engine.Stats()
+}
 
-	select {
-	case b.workerChan <- batchToSend:
-	default:
-		go b.processBatch(batchToSend)
-	}
+func (b *Batcher) Flush() {
+	b.engine.Flush()
 }
 
-func (b *Batcher) worker(ctx context.Context) {
-	for {
-		select {
-		case batch, ok := <-b.workerChan:
-			if !ok {
-				return
+// track looks trackCodes up and delivers every code as soon as its page is
+// parsed. Fresh data goes to the cache and the result hooks once per code.
+func (b *Batcher) track(ctx context.Context, trackCodes []string, deliver func(string, *models.TrackData, error)) error {
+	var (
+		mu     sync.Mutex
+		stored = make(map[string]bool, len(trackCodes))
+	)
+	found := func(results map[string]*models.TrackData) {
+		for trackCode, trackData := range results {
+			mu.Lock()
+			seen := stored[trackCode]
+			stored[trackCode] = true
+			mu.Unlock()
+
+			if !seen {
+				b.deliverData(trackCode, trackData, deliver)
 			}
-			b.processBatch(batch)
-		case <-ctx.Done():
-			return
 		}
 	}
-}
 
-func (b *Batcher) processBatch(items []batchItem) {
-	if len(items) == 0 {
-		return
+	batchID := client.NewBatchID()
+	ctx = client.WithBatchID(ctx, batchID)
+	ctx = client.WithResultSink(ctx, found)
+	if b.pageGate != nil {
+		ctx = client.WithPageGate(ctx, b.pageGate)
 	}
 
-	trackCodes := make([]string, 0, len(items))
-	for _, item := range items {
-		trackCodes = append(trackCodes, item.trackCode)
-	}
-
-	results, err := b.client.TrackPackagesBatch(context.Background(), trackCodes)
-	if err != nil {
-		log.Printf("batcher.processBatch.APIError: %v", err)
+	results, err := b.client.TrackPackagesBatch(ctx, trackCodes)
+	found(results)
 
-		var errorMsg string
-		if erors.IsClientError(err) {
-			errorMsg = err.Error()
-		} else {
-			errorMsg = "tracking service temporarily unavailable"
+	if partialErr, ok := erors.AsPartialBatch(err); ok {
+		log.Printf("batcher.processBatch.PartialError: batch %s: %v", batchID, err)
+		for trackCode, codeErr := range partialErr.Failed {
+			deliver(trackCode, nil, codeErr)
 		}
+		err = nil
+	}
 
-		errorResponse := models.TrackResponse{
-			Status: false,
-			Error:  errorMsg,
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("batcher.processBatch.Deadline: %d codes after %s", len(trackCodes), b.config.BatchDeadline)
+			err = erors.NewClientError("request timeout", erors.ErrRequestTimeout)
 		}
-
-		for _, item := range items {
-			select {
-			case item.responseChan <- errorResponse:
-			case <-time.After(100 * time.Millisecond):
-				log.Printf("Client timeout for track code: %s", item.trackCode)
+		log.Printf("batcher.processBatch.APIError: batch %s: %v", batchID, err)
+	} else {
+		for _, trackCode := range trackCodes {
+			if _, exists := results[trackCode]; !exists {
+				log.Printf("No data found for track code: %s", trackCode)
 			}
 		}
-		return
 	}
 
-	for _, item := range items {
-		if trackData, exists := results[item.trackCode]; exists {
-			if err := b.cache.SetTrackData(context.Background(), item.trackCode, trackData, 5*time.Minute); err != nil {
-				log.Printf("Cache set error for %s: %v", item.trackCode, err)
-			}
-
-			successResponse := models.TrackResponse{
-				Status: true,
-				Data:   trackData,
-			}
+	log.Printf("Batch %s processing completed: %d/%d successful", batchID, len(results), len(trackCodes))
+	return err
+}
 
-			select {
-			case item.responseChan <- successResponse:
-			case <-time.After(100 * time.Millisecond):
-				log.Printf("Client timeout for successful response: %s", item.trackCode)
-			}
+func (b *Batcher) deliverData(trackCode string, trackData *models.TrackData, deliver func(string, *models.TrackData, error)) {
+	if err := b.cache.SetTrackData(context.Background(), trackCode, trackData, b.config.CacheTTL); err != nil {
+		log.Printf("Cache set error for %s: %v", trackCode, err)
+	}
 
-		} else {
-			notFoundResponse := models.TrackResponse{
-				Status: false,
-				Error:  "tracking code not found in external system",
-			}
+	deliver(trackCode, trackData, nil)
 
-			select {
-			case item.responseChan <- notFoundResponse:
-			case <-time.After(100 * time.Millisecond):
-				log.Printf("Client timeout for not-found response: %s", item.trackCode)
-			}
+	for _, hook := range b.resultHooks {
+		hook(context.Background(), trackCode, trackData)
+	}
+}
 
-			log.Printf("No data found for track code: %s", item.trackCode)
+func trackResponse(result batching.Result[*models.TrackData]) models.TrackResponse {
+	if result.Err == nil {
+		return models.TrackResponse{
+			Status: true,
+			Data:   result.Value,
 		}
 	}
 
-	successful := 0
-	for _, item := range items {
-		if _, exists := results[item.trackCode]; exists {
-			successful++
-		}
+	response := models.TrackResponse{
+		Status: false,
+		Error:  errorMessage(result.Err),
+	}
+	var rejected *batching.RejectedError
+	if errors.As(result.Err, &rejected) {
+		response.RetryAfter = rejected.RetryAfter
+	} else if blockedErr, ok := erors.AsProviderBlocked(result.Err); ok {
+		// The provider backoff tells callers when a retry can succeed.
+		response.RetryAfter = blockedErr.RetryAfter
 	}
+	return response
+}
 
-	log.Printf("Batch processing completed: %d/%d successful", successful, len(items))
+// errorMessage turns an error into the message returned to callers.
+// Internal errors are hidden behind a generic message.
+func errorMessage(err error) string {
+	switch {
+	case errors.Is(err, batching.ErrQueueFull), errors.Is(err, batching.ErrOverflow):
+		return "service busy, try again later"
+	case errors.Is(err, batching.ErrShuttingDown):
+		return "service shutting down"
+	case errors.Is(err, batching.ErrCancelled):
+		return "request cancelled"
+	case errors.Is(err, batching.ErrNotFound):
+		return "tracking code not found in external system"
+	case erors.IsClientError(err):
+		return err.Error()
+	default:
+		return "tracking service temporarily unavailable"
+	}
 }
@@ -3,18 +3,19 @@ package batcher
 import (
 	"context"
 
+	"github.com/shamil/proxy_track_service-1/internal/batching"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 )
 
+// Stats is the queue and worker snapshot reported on /health.
+type Stats = batching.Stats
+
 type BatcherInterface interface {
-	AddRequest(ctx context.Context, trackCode string) <-chan models.TrackResponse
+	AddRequest(ctx context.Context, trackCode string, priority Priority) <-chan models.TrackResponse
 	Start(ctx context.Context) error
 	Stop() error
+	Shutdown(ctx context.Context) error
 	Health(ctx context.Context) error
+	Stats() Stats
 	Flush()
-
-	addToBatch(req batchRequest)
-	flushBatchLocked()
-	processBatch(items []batchItem)
-	worker(ctx context.Context)
 }
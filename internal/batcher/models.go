@@ -3,16 +3,45 @@ package batcher
 import (
 	"context"
 
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/clock"
 	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
 )
 
-type batchItem struct {
-	trackCode    string
-	responseChan chan models.TrackResponse
+// ResultHook is called for every track code the provider returned data for,
+// after the caller has been answered.
+type ResultHook func(ctx context.Context, trackCode string, data *models.TrackData)
+
+type Option func(*Batcher)
+
+// WithResultHook registers hook to observe fresh provider results.
+func WithResultHook(hook ResultHook) Option {
+	return func(b *Batcher) {
+		b.resultHooks = append(b.resultHooks, hook)
+	}
+}
+
+// WithPendingQueue saves requests left over at shutdown to queue and fetches
+// them again on start.
+func WithPendingQueue(queue repository.PendingQueueRepository) Option {
+	return func(b *Batcher) {
+		b.pendingQueue = queue
+	}
+}
+
+// WithPageGate makes every page of a batch wait for gate before it is
+// scraped.
+func WithPageGate(gate client.PageGate) Option {
+	return func(b *Batcher) {
+		b.pageGate = gate
+	}
 }
 
-type batchRequest struct {
-	ctx         context.Context
-	trackCode   string
-	respChannel chan models.TrackResponse
+// WithClock runs flush timeouts and queue waits on c instead of the system
+// clock.
+func WithClock(c clock.Clock) Option {
+	return func(b *Batcher) {
+		b.clock = c
+	}
 }
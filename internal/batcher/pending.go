@@ -0,0 +1,75 @@
+package batcher
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/batching"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// persistTimeout bounds saving leftovers to the pending queue.
+const persistTimeout = 5 * time.Second
+
+// persist saves the codes shutdown could not finish to the pending queue.
+func (b *Batcher) persist(leftovers []batching.Leftover[string]) {
+	requests := make([]models.PendingRequest, 0, len(leftovers))
+	for _, leftover := range leftovers {
+		requests = append(requests, models.PendingRequest{
+			TrackCode: leftover.Key,
+			Priority:  leftover.Priority.String(),
+			QueuedAt:  leftover.QueuedAt,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	if err := b.pendingQueue.Push(ctx, requests); err != nil {
+		log.Printf("batcher.persist.Error: %d codes: %v", len(requests), err)
+		return
+	}
+	log.Printf("batcher.persist.Saved: %d codes", len(requests))
+	metrics.BatcherPersisted.Add(int64(len(requests)))
+}
+
+// recoverPending queues the requests saved by the previous shutdown. Nobody
+// waits for them; their results reach the cache and the result hooks.
+// Requests turned away again are put back for the next start.
+func (b *Batcher) recoverPending(ctx context.Context) {
+	requests, err := b.pendingQueue.PopAll(ctx)
+	if err != nil {
+		log.Printf("batcher.recoverPending.Error: %v", err)
+		return
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	log.Printf("batcher.recoverPending.Restored: %d codes", len(requests))
+	metrics.BatcherRecovered.Add(int64(len(requests)))
+
+	var rejected []models.PendingRequest
+	for _, request := range requests {
+		priority, err := ParsePriority(request.Priority)
+		if err != nil {
+			priority = PriorityBulk
+		}
+
+		select {
+		case response := <-b.AddRequest(ctx, request.TrackCode, priority):
+			if !response.Status {
+				rejected = append(rejected, request)
+			}
+		default:
+		}
+	}
+
+	if len(rejected) > 0 {
+		if err := b.pendingQueue.Push(context.Background(), rejected); err != nil {
+			log.Printf("batcher.recoverPending.Error: %d codes lost: %v", len(rejected), err)
+		}
+	}
+}
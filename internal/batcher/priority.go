@@ -0,0 +1,19 @@
+package batcher
+
+import "github.com/shamil/proxy_track_service-1/internal/batching"
+
+// Priority selects the lane a request waits in.
+type Priority = batching.Priority
+
+const (
+	// PriorityInteractive is for customers waiting on a page.
+	PriorityInteractive = batching.PriorityInteractive
+	PriorityNormal      = batching.PriorityNormal
+	// PriorityBulk is for background re-checks.
+	PriorityBulk = batching.PriorityBulk
+)
+
+// ParsePriority accepts the names returned by Priority.String.
+func ParsePriority(name string) (Priority, error) {
+	return batching.ParsePriority(name)
+}
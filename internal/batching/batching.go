@@ -0,0 +1,443 @@
+// Package batching groups keys submitted by many callers into batches,
+// processes each batch with a single call and fans the results back out.
+//
+// Keys wait in three priority lanes with their own bounded queues. Batches
+// are drawn from the lanes by weight, flushed by size or timeout as decided
+// by a FlushPolicy and handed to a fixed pool of workers. Callers that share
+// a key in a batch share its result, and keys whose callers all left are
+// skipped.
+package batching
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/clock"
+)
+
+// BatchFunc processes keys and reports each result through deliver as soon
+// as it is known; deliver may be called concurrently. Keys left undelivered
+// when it returns get the returned error, or ErrNotFound if it is nil.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K, deliver func(key K, value V, err error)) error
+
+// Result is what a caller gets for a submitted key.
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// Leftover is a key that was still pending when shutdown ran out of time.
+type Leftover[K comparable] struct {
+	Key      K
+	Priority Priority
+	QueuedAt time.Time
+}
+
+type Option[K comparable, V any] func(*Batcher[K, V])
+
+// WithLeftovers registers save to receive the keys Shutdown could not
+// finish, once per key, before their callers are answered.
+func WithLeftovers[K comparable, V any](save func(leftovers []Leftover[K])) Option[K, V] {
+	return func(b *Batcher[K, V]) {
+		b.leftovers = save
+	}
+}
+
+type item[K comparable, V any] struct {
+	ctx        context.Context
+	key        K
+	priority   Priority
+	enqueuedAt time.Time
+	// reply answers the caller.
+	reply func(Result[V])
+}
+
+type Batcher[K comparable, V any] struct {
+	config    Config
+	fn        BatchFunc[K, V]
+	leftovers func([]Leftover[K])
+
+	mu          sync.Mutex
+	lanes       [laneCount][]item[K, V]
+	batchTimer  clock.Timer
+	inputChans  [laneCount]chan item[K, V]
+	workerChan  chan []item[K, V]
+	flushSignal chan struct{}
+	wakeup      chan struct{}
+	stopChan    chan struct{}
+
+	// closing stops Submit and makes the main loop flush everything and
+	// exit, closing drained. abortCtx is the parent of every batch call and
+	// is cancelled when draining runs out of time.
+	closing   chan struct{}
+	drained   chan struct{}
+	inflight  sync.WaitGroup
+	abortCtx  context.Context
+	abort     context.CancelFunc
+	closeOnce sync.Once
+	stopOnce  sync.Once
+
+	// statsMu guards what Stats reports about the workers.
+	statsMu     sync.Mutex
+	busySince   []time.Time
+	lastSuccess time.Time
+}
+
+func New[K comparable, V any](cfg Config, fn BatchFunc[K, V], opts ...Option[K, V]) *Batcher[K, V] {
+	cfg = withDefaults(cfg)
+
+	b := &Batcher[K, V]{
+		config:      cfg,
+		fn:          fn,
+		workerChan:  make(chan []item[K, V], cfg.Workers),
+		flushSignal: make(chan struct{}, 1),
+		wakeup:      make(chan struct{}, 1),
+		stopChan:    make(chan struct{}),
+		closing:     make(chan struct{}),
+		drained:     make(chan struct{}),
+		busySince:   make([]time.Time, cfg.Workers),
+	}
+	b.abortCtx, b.abort = context.WithCancel(context.Background())
+	for p := range b.inputChans {
+		b.inputChans[p] = make(chan item[K, V], cfg.QueueCapacity)
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.batchTimer = cfg.Clock.NewTimer(0)
+	if !b.batchTimer.Stop() {
+		<-b.batchTimer.C()
+	}
+
+	return b
+}
+
+// Submit queues key and returns a channel that receives its result.
+func (b *Batcher[K, V]) Submit(ctx context.Context, key K, priority Priority) <-chan Result[V] {
+	resultChan := make(chan Result[V], 1)
+	b.SubmitFunc(ctx, key, priority, func(result Result[V]) {
+		resultChan <- result
+	})
+	return resultChan
+}
+
+// SubmitFunc queues key in the lane of priority; reply is called exactly
+// once with its result and must not block. Each lane has its own queue, so a
+// flood of bulk keys cannot turn interactive ones away. When the lane is
+// full the caller waits up to EnqueueTimeout for room before it is rejected
+// with a RetryAfter hint.
+func (b *Batcher[K, V]) SubmitFunc(ctx context.Context, key K, priority Priority, reply func(Result[V])) {
+	if !priority.valid() {
+		priority = PriorityNormal
+	}
+
+	it := item[K, V]{
+		ctx:      ctx,
+		key:      key,
+		priority: priority,
+		reply:    reply,
+	}
+
+	select {
+	case <-b.closing:
+		b.reject(it, ErrShuttingDown)
+		return
+	default:
+	}
+
+	select {
+	case b.inputChans[priority] <- it:
+		return
+	default:
+	}
+
+	if b.config.EnqueueTimeout <= 0 {
+		b.reject(it, ErrQueueFull)
+		return
+	}
+
+	timer := b.config.Clock.NewTimer(b.config.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case b.inputChans[priority] <- it:
+	case <-ctx.Done():
+		b.reject(it, ErrCancelled)
+	case <-b.closing:
+		b.reject(it, ErrShuttingDown)
+	case <-timer.C():
+		log.Printf("batching.Submit.QueueFull: %s lane, %v", priority, key)
+		b.reject(it, ErrQueueFull)
+	}
+}
+
+func (b *Batcher[K, V]) Start(ctx context.Context) error {
+	for i := 0; i < b.config.Workers; i++ {
+		go b.worker(ctx, i)
+	}
+
+	go b.timerManager(ctx)
+
+	go b.mainLoop(ctx)
+
+	return nil
+}
+
+func (b *Batcher[K, V]) mainLoop(ctx context.Context) {
+	defer close(b.drained)
+
+	for {
+		select {
+		case it := <-b.inputChans[PriorityInteractive]:
+			b.dispatch(ctx, b.add(it))
+
+		case it := <-b.inputChans[PriorityNormal]:
+			b.dispatch(ctx, b.add(it))
+
+		case it := <-b.inputChans[PriorityBulk]:
+			b.dispatch(ctx, b.add(it))
+
+		case <-b.flushSignal:
+			b.mu.Lock()
+			batch := b.flushLocked()
+			b.mu.Unlock()
+			b.dispatch(ctx, batch)
+
+		case <-b.closing:
+			b.flushAll(ctx)
+			return
+
+		case <-ctx.Done():
+			// Workers stop with ctx, so whatever is still pending cannot be
+			// processed.
+			b.leave(b.takePending())
+			return
+
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// timerManager sleeps until the earliest lane deadline and then asks the main
+// loop to flush. It is woken up whenever a lane gets a new head item.
+func (b *Batcher[K, V]) timerManager(ctx context.Context) {
+	for {
+		b.mu.Lock()
+		deadline, hasItems := b.nextDeadlineLocked()
+		b.mu.Unlock()
+
+		if hasItems {
+			b.batchTimer.Reset(b.config.Clock.Until(deadline))
+
+			select {
+			case <-b.batchTimer.C():
+				select {
+				case b.flushSignal <- struct{}{}:
+				default:
+				}
+			case <-b.wakeup:
+				if !b.batchTimer.Stop() {
+					select {
+					case <-b.batchTimer.C():
+					default:
+					}
+				}
+			case <-ctx.Done():
+				return
+			case <-b.stopChan:
+				return
+			}
+		} else {
+			select {
+			case <-b.wakeup:
+			case <-ctx.Done():
+				return
+			case <-b.stopChan:
+				return
+			}
+		}
+	}
+}
+
+func (b *Batcher[K, V]) wake() {
+	select {
+	case b.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Stop fails every pending key right away; Shutdown drains them first.
+func (b *Batcher[K, V]) Stop() error {
+	b.closeOnce.Do(func() { close(b.closing) })
+	b.stopOnce.Do(func() { close(b.stopChan) })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.batchTimer.Stop() {
+		select {
+		case <-b.batchTimer.C():
+		default:
+		}
+	}
+
+	for p := range b.lanes {
+		b.rejectAll(b.lanes[p], ErrShuttingDown)
+		b.lanes[p] = nil
+	}
+
+	return nil
+}
+
+// Flush asks the main loop to send the pending keys without waiting for the
+// flush timeout.
+func (b *Batcher[K, V]) Flush() {
+	select {
+	case b.flushSignal <- struct{}{}:
+	default:
+	}
+}
+
+// add queues it and returns a batch to dispatch once the lanes hold a full
+// batch.
+func (b *Batcher[K, V]) add(it item[K, V]) []item[K, V] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if it.ctx != nil && it.ctx.Err() != nil {
+		b.reject(it, ErrCancelled)
+		return nil
+	}
+
+	it.enqueuedAt = b.config.Clock.Now()
+	lane := &b.lanes[it.priority]
+	*lane = append(*lane, it)
+	if len(*lane) == 1 {
+		b.wake()
+	}
+
+	if b.pendingLocked() >= b.config.Policy.BatchSize() {
+		return b.flushLocked()
+	}
+	return nil
+}
+
+// flushLocked takes the next batch off the lanes. The caller dispatches it
+// after releasing the lock, since dispatch may block.
+func (b *Batcher[K, V]) flushLocked() []item[K, V] {
+	batch := b.takeBatchLocked()
+	if len(batch) > 0 {
+		b.config.Policy.ObserveFlush(len(batch), b.pendingLocked())
+		b.wake()
+	}
+	return batch
+}
+
+func (b *Batcher[K, V]) pendingLocked() int {
+	pending := 0
+	for p := range b.lanes {
+		pending += len(b.lanes[p])
+	}
+	return pending
+}
+
+// nextDeadlineLocked returns when the oldest item of any lane is due, using
+// the lane's flush timeout.
+func (b *Batcher[K, V]) nextDeadlineLocked() (time.Time, bool) {
+	var deadline time.Time
+	found := false
+	for p := range b.lanes {
+		if len(b.lanes[p]) == 0 {
+			continue
+		}
+		due := b.lanes[p][0].enqueuedAt.Add(b.laneTimeout(Priority(p)))
+		if !found || due.Before(deadline) {
+			deadline, found = due, true
+		}
+	}
+	return deadline, found
+}
+
+// takeBatchLocked assembles up to a batch size of items. Items waiting longer
+// than StarvationTimeout go first, oldest first; the rest is drained in
+// weighted rounds, taking up to the lane weight from each lane in priority
+// order.
+func (b *Batcher[K, V]) takeBatchLocked() []item[K, V] {
+	size := b.config.Policy.BatchSize()
+	batch := make([]item[K, V], 0, size)
+
+	starvedBefore := b.config.Clock.Now().Add(-b.config.StarvationTimeout)
+	for len(batch) < size {
+		oldest := -1
+		for p := range b.lanes {
+			if len(b.lanes[p]) == 0 || !b.lanes[p][0].enqueuedAt.Before(starvedBefore) {
+				continue
+			}
+			if oldest < 0 || b.lanes[p][0].enqueuedAt.Before(b.lanes[oldest][0].enqueuedAt) {
+				oldest = p
+			}
+		}
+		if oldest < 0 {
+			break
+		}
+		batch = append(batch, b.lanes[oldest][0])
+		b.lanes[oldest] = b.lanes[oldest][1:]
+	}
+
+	for len(batch) < size && b.pendingLocked() > 0 {
+		for p := range b.lanes {
+			take := min(b.laneWeight(Priority(p)), len(b.lanes[p]), size-len(batch))
+			batch = append(batch, b.lanes[p][:take]...)
+			b.lanes[p] = b.lanes[p][take:]
+		}
+	}
+
+	for p := range b.lanes {
+		if len(b.lanes[p]) == 0 {
+			b.lanes[p] = nil
+		}
+	}
+
+	return batch
+}
+
+func (b *Batcher[K, V]) worker(ctx context.Context, id int) {
+	for {
+		select {
+		case batch := <-b.workerChan:
+			b.setBusy(id, b.config.Clock.Now())
+			b.process(batch)
+			b.setBusy(id, time.Time{})
+			b.inflight.Done()
+		case <-ctx.Done():
+			return
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// reject answers an item that will not be processed.
+func (b *Batcher[K, V]) reject(it item[K, V], err error) {
+	reason := rejectReason(err)
+	addTo(b.config.Metrics.Rejected, reason, 1)
+	addTo(b.config.Metrics.Rejected, reason+"_"+it.priority.String(), 1)
+
+	rejected := &RejectedError{Err: err}
+	if !errors.Is(err, ErrCancelled) {
+		rejected.RetryAfter = b.config.RetryAfter
+	}
+	it.reply(Result[V]{Err: rejected})
+}
+
+func (b *Batcher[K, V]) rejectAll(items []item[K, V], err error) {
+	for _, it := range items {
+		b.reject(it, err)
+	}
+}
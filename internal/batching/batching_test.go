@@ -0,0 +1,181 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/clock"
+)
+
+// TestBatcherEndToEnd - ключи группируются в батч, дубликаты получают общий результат
+func TestBatcherEndToEnd(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+	b := New(Config{BatchSize: 3, BatchTimeout: time.Second}, func(ctx context.Context, keys []int, deliver func(int, int, error)) error {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+		for _, key := range keys {
+			deliver(key, key*key, nil)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Stop()
+
+	results := []<-chan Result[int]{
+		b.Submit(ctx, 2, PriorityNormal),
+		b.Submit(ctx, 3, PriorityNormal),
+		b.Submit(ctx, 2, PriorityNormal),
+	}
+	for i, want := range []int{4, 9, 4} {
+		select {
+		case result := <-results[i]:
+			if result.Err != nil || result.Value != want {
+				t.Errorf("result %d = %+v, want %d", i, result, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("result %d not delivered", i)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Errorf("batches = %v, want one batch of [2 3]", batches)
+	}
+}
+
+// TestBatcherShutdownLeftovers - необработанные при остановке ключи отдаются в WithLeftovers
+func TestBatcherShutdownLeftovers(t *testing.T) {
+	var leftovers []Leftover[string]
+	b := New(Config{BatchSize: 10, BatchTimeout: time.Minute},
+		func(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		WithLeftovers[string, int](func(l []Leftover[string]) {
+			leftovers = append(leftovers, l...)
+		}),
+	)
+
+	if err := b.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	result := b.Submit(context.Background(), "STUCK", PriorityBulk)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	b.Shutdown(ctx)
+
+	if got := <-result; !errors.Is(got.Err, ErrShuttingDown) {
+		t.Errorf("got %+v, want ErrShuttingDown", got)
+	}
+	if len(leftovers) != 1 || leftovers[0].Key != "STUCK" || leftovers[0].Priority != PriorityBulk {
+		t.Errorf("leftovers = %+v, want [STUCK]", leftovers)
+	}
+}
+
+// TestBatcherHealthIsPassive - проверка здоровья не ставит ключи в очередь
+func TestBatcherHealthIsPassive(t *testing.T) {
+	calls := make(chan []string, 10)
+	b := New(Config{BatchSize: 1, BatchTimeout: 10 * time.Millisecond},
+		func(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+			calls <- keys
+			return nil
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Stop()
+
+	for i := 0; i < 5; i++ {
+		if err := b.Health(ctx); err != nil {
+			t.Fatalf("Health() = %v", err)
+		}
+	}
+
+	select {
+	case keys := <-calls:
+		t.Fatalf("health check reached the batch function with %v", keys)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if stats := b.Stats(); stats.Queued != 0 || stats.LastSuccessAt != nil {
+		t.Errorf("stats = %+v, want an idle batcher", stats)
+	}
+}
+
+// TestBatcherStats - глубина очереди, возраст старейшего ключа и состояние воркеров
+func TestBatcherStats(t *testing.T) {
+	b := newLaneBatcher(10)
+	now := time.Now()
+	b.fillLane(PriorityBulk, []string{"B1", "B2"}, now.Add(-time.Minute))
+	b.fillLane(PriorityInteractive, []string{"I1"}, now)
+	b.busySince[0] = now.Add(-time.Hour)
+	b.markSuccess()
+
+	stats := b.Stats()
+	if stats.Queued != 3 || stats.Lanes["bulk"] != 2 || stats.Lanes["interactive"] != 1 {
+		t.Errorf("queued = %d, lanes = %v, want 3 with 2 bulk and 1 interactive", stats.Queued, stats.Lanes)
+	}
+	if stats.OldestPendingSeconds < 60 {
+		t.Errorf("oldest pending = %.0fs, want at least 60s", stats.OldestPendingSeconds)
+	}
+	if stats.ActiveWorkers != 1 || stats.StuckWorkers != 1 {
+		t.Errorf("active = %d, stuck = %d, want 1 and 1", stats.ActiveWorkers, stats.StuckWorkers)
+	}
+	if stats.LastSuccessAt == nil {
+		t.Errorf("last success not reported")
+	}
+
+	if err := b.Health(context.Background()); err == nil {
+		t.Errorf("Health() succeeded with every worker stuck")
+	}
+}
+
+// TestBatcherEnqueueTimeout - при полной очереди клиент ждет EnqueueTimeout по часам батчера
+func TestBatcherEnqueueTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(Config{
+		BatchSize:      10,
+		BatchTimeout:   time.Second,
+		QueueCapacity:  1,
+		EnqueueTimeout: 500 * time.Millisecond,
+		RetryAfter:     3 * time.Second,
+		Clock:          fake,
+	}, func(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+		return nil
+	})
+
+	// Not started, so the first key fills the lane and the second waits.
+	b.Submit(context.Background(), "FIRST", PriorityNormal)
+	result := make(chan (<-chan Result[int]), 1)
+	go func() {
+		result <- b.Submit(context.Background(), "SECOND", PriorityNormal)
+	}()
+	fake.BlockUntil(1)
+
+	select {
+	case <-result:
+		t.Fatal("rejected before the enqueue timeout")
+	default:
+	}
+
+	fake.Advance(500 * time.Millisecond)
+	got := <-<-result
+	var rejected *RejectedError
+	if !errors.As(got.Err, &rejected) || !errors.Is(got.Err, ErrQueueFull) || rejected.RetryAfter != 3*time.Second {
+		t.Errorf("got %+v, want ErrQueueFull with a 3s Retry-After", got)
+	}
+}
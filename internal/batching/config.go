@@ -0,0 +1,129 @@
+package batching
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/clock"
+)
+
+// Config tunes a Batcher. Zero values get the defaults noted on each field.
+type Config struct {
+	// BatchSize and BatchTimeout are used when Policy is nil: a batch is
+	// flushed once it holds BatchSize keys or its oldest key waited
+	// BatchTimeout.
+	BatchSize    int
+	BatchTimeout time.Duration
+	// Policy decides the batch size and flush timeout as the batcher runs.
+	Policy FlushPolicy
+	// Workers is the number of batches processed at once, 1 by default.
+	Workers int
+
+	// InteractiveTimeout is the flush timeout of the interactive lane,
+	// capped at the policy timeout.
+	InteractiveTimeout time.Duration
+	// StarvationTimeout puts keys waiting that long at the head of the next
+	// batch regardless of their lane; 10 flush timeouts by default.
+	StarvationTimeout time.Duration
+	// Lane weights set how many keys of each lane are taken per round when
+	// a batch is assembled; 6, 3 and 1 by default.
+	InteractiveWeight int
+	NormalWeight      int
+	BulkWeight        int
+
+	// QueueCapacity is the number of keys each lane buffers before callers
+	// have to wait; twice the batch size by default.
+	QueueCapacity int
+	// EnqueueTimeout is how long a caller waits for room in a full lane;
+	// zero turns it away at once.
+	EnqueueTimeout time.Duration
+	// RetryAfter is the hint returned with rejections; the flush timeout by
+	// default.
+	RetryAfter time.Duration
+	// Overflow decides what happens to a batch when every worker is busy.
+	Overflow OverflowPolicy
+
+	// BatchDeadline bounds a single call of the batch function; 2 minutes by
+	// default.
+	BatchDeadline time.Duration
+
+	// Metrics receives counters; nil disables them.
+	Metrics *Metrics
+	// Clock drives flush timeouts, enqueue waits and stats; the system
+	// clock by default. Batch deadlines and the shutdown grace are context
+	// deadlines and always use the system clock.
+	Clock clock.Clock
+}
+
+// Metrics are the expvar counters a Batcher updates. Any of them may be nil.
+type Metrics struct {
+	// Rejected counts keys turned away, by reason and by reason and lane.
+	Rejected *expvar.Map
+	// Overflow counts overflow policy triggers, by policy.
+	Overflow *expvar.Map
+	// Cancelled counts keys whose callers left, before_scrape and
+	// during_scrape.
+	Cancelled *expvar.Map
+
+	// The current decisions and inputs of an adaptive policy.
+	BatchSize    *expvar.Int
+	FlushTimeout *expvar.Int
+	Latency      *expvar.Int
+	ErrorRate    *expvar.Float
+}
+
+func addTo(counters *expvar.Map, key string, delta int64) {
+	if counters != nil {
+		counters.Add(key, delta)
+	}
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.Policy == nil {
+		cfg.Policy = StaticPolicy(cfg.BatchSize, cfg.BatchTimeout)
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	timeout := cfg.Policy.Timeout()
+	if cfg.InteractiveTimeout <= 0 || cfg.InteractiveTimeout > timeout {
+		cfg.InteractiveTimeout = timeout
+	}
+	if cfg.StarvationTimeout <= 0 {
+		cfg.StarvationTimeout = 10 * timeout
+	}
+	if cfg.InteractiveWeight <= 0 {
+		cfg.InteractiveWeight = 6
+	}
+	if cfg.NormalWeight <= 0 {
+		cfg.NormalWeight = 3
+	}
+	if cfg.BulkWeight <= 0 {
+		cfg.BulkWeight = 1
+	}
+
+	if cfg.QueueCapacity <= 0 {
+		cfg.QueueCapacity = cfg.Policy.BatchSize() * 2
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = timeout
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowBlock
+	}
+
+	if cfg.BatchDeadline <= 0 {
+		cfg.BatchDeadline = 2 * time.Minute
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = &Metrics{}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
+	return cfg
+}
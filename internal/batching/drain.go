@@ -0,0 +1,161 @@
+package batching
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// abortGrace is how long aborted batches get to hand their items back.
+const abortGrace = 5 * time.Second
+
+// Shutdown stops accepting keys, sends everything pending to the workers and
+// waits for in-flight batches until ctx is done. Batches still running then
+// are aborted; their keys and any others left over go to the leftovers
+// callback, if set, and their callers are answered with ErrShuttingDown.
+func (b *Batcher[K, V]) Shutdown(ctx context.Context) error {
+	b.closeOnce.Do(func() { close(b.closing) })
+
+	select {
+	case <-b.drained:
+	case <-ctx.Done():
+		log.Printf("batching.Shutdown.DrainTimeout: pending keys were not flushed")
+		b.abort()
+		waitClosed(b.drained, abortGrace)
+	}
+
+	if !waitGroup(ctx, &b.inflight) {
+		log.Printf("batching.Shutdown.DrainTimeout: aborting in-flight batches")
+		b.abort()
+		b.leaveQueuedBatches()
+
+		graceCtx, cancel := context.WithTimeout(context.Background(), abortGrace)
+		defer cancel()
+		waitGroup(graceCtx, &b.inflight)
+	}
+
+	b.leave(b.takePending())
+	b.abort()
+
+	return b.Stop()
+}
+
+// flushAll moves every queued key into batches and dispatches them.
+func (b *Batcher[K, V]) flushAll(ctx context.Context) {
+	for p := range b.inputChans {
+		for drained := false; !drained; {
+			select {
+			case it := <-b.inputChans[p]:
+				b.dispatch(ctx, b.add(it))
+			default:
+				drained = true
+			}
+		}
+	}
+
+	for {
+		b.mu.Lock()
+		batch := b.flushLocked()
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		b.dispatch(ctx, batch)
+	}
+}
+
+// takePending empties the lanes and the input queues.
+func (b *Batcher[K, V]) takePending() []item[K, V] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var items []item[K, V]
+	for p := range b.lanes {
+		items = append(items, b.lanes[p]...)
+		b.lanes[p] = nil
+	}
+
+	for p := range b.inputChans {
+		for drained := false; !drained; {
+			select {
+			case it := <-b.inputChans[p]:
+				it.enqueuedAt = b.config.Clock.Now()
+				items = append(items, it)
+			default:
+				drained = true
+			}
+		}
+	}
+
+	return items
+}
+
+// leaveQueuedBatches gives up on batches no worker has picked up yet.
+func (b *Batcher[K, V]) leaveQueuedBatches() {
+	for {
+		select {
+		case batch := <-b.workerChan:
+			b.leave(batch)
+			b.inflight.Done()
+		default:
+			return
+		}
+	}
+}
+
+// leave hands the keys of items to the leftovers callback, if any, and
+// answers their callers.
+func (b *Batcher[K, V]) leave(items []item[K, V]) {
+	if len(items) == 0 {
+		return
+	}
+
+	if b.leftovers != nil {
+		b.leftovers(leftoversOf(items))
+	}
+
+	b.rejectAll(items, ErrShuttingDown)
+}
+
+// leftoversOf turns items into one leftover per key.
+func leftoversOf[K comparable, V any](items []item[K, V]) []Leftover[K] {
+	seen := make(map[K]bool, len(items))
+	leftovers := make([]Leftover[K], 0, len(items))
+	for _, it := range items {
+		if seen[it.key] {
+			continue
+		}
+		seen[it.key] = true
+		leftovers = append(leftovers, Leftover[K]{
+			Key:      it.key,
+			Priority: it.priority,
+			QueuedAt: it.enqueuedAt,
+		})
+	}
+	return leftovers
+}
+
+// waitGroup waits for wg until ctx is done and reports whether wg finished.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func waitClosed(ch <-chan struct{}, timeout time.Duration) {
+	select {
+	case <-ch:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,50 @@
+package batching
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned when a lane stayed full for EnqueueTimeout.
+	ErrQueueFull = errors.New("batching: queue full")
+	// ErrOverflow is returned for batches dropped by an overflow policy.
+	ErrOverflow = errors.New("batching: no free worker")
+	// ErrShuttingDown is returned for keys submitted or left over during
+	// shutdown.
+	ErrShuttingDown = errors.New("batching: shutting down")
+	// ErrCancelled is returned when the caller's context ended before its key
+	// was processed.
+	ErrCancelled = errors.New("batching: cancelled")
+	// ErrNotFound is returned for keys the batch function gave no result for.
+	ErrNotFound = errors.New("batching: no result")
+)
+
+// RejectedError is returned for keys that were never processed, with a hint
+// on when to try again.
+type RejectedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// rejectReason names err in the rejection metrics.
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		return "queue_full"
+	case errors.Is(err, ErrOverflow):
+		return "overflow"
+	case errors.Is(err, ErrShuttingDown):
+		return "shutting_down"
+	default:
+		return "cancelled"
+	}
+}
@@ -0,0 +1,90 @@
+package batching
+
+import (
+	"context"
+	"log"
+)
+
+// OverflowPolicy decides what happens to a batch when every worker is busy
+// and the worker queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock holds the batch until a worker frees up; new keys back
+	// up in the lane queues meanwhile.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowRejectOldest drops the longest queued batch to make room.
+	OverflowRejectOldest OverflowPolicy = "reject_oldest"
+	// OverflowRejectNewest drops the batch that did not fit.
+	OverflowRejectNewest OverflowPolicy = "reject_newest"
+	// OverflowMerge appends the batch to one already waiting for a worker.
+	OverflowMerge OverflowPolicy = "merge"
+)
+
+// Valid reports whether p is one of the known policies.
+func (p OverflowPolicy) Valid() bool {
+	switch p {
+	case OverflowBlock, OverflowRejectOldest, OverflowRejectNewest, OverflowMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatch hands batch to the worker pool. Only the main loop calls it, so
+// it is the single sender on workerChan. When the queue is full the
+// configured overflow policy decides what happens; the number of batches
+// processed at once never exceeds Workers.
+func (b *Batcher[K, V]) dispatch(ctx context.Context, batch []item[K, V]) {
+	if len(batch) == 0 {
+		return
+	}
+
+	b.inflight.Add(1)
+	select {
+	case b.workerChan <- batch:
+		return
+	default:
+	}
+
+	policy := b.config.Overflow
+	addTo(b.config.Metrics.Overflow, string(policy), 1)
+
+	switch policy {
+	case OverflowRejectNewest:
+		log.Printf("batching.dispatch.Overflow: %s, dropping %d keys", policy, len(batch))
+		b.rejectAll(batch, ErrOverflow)
+		b.inflight.Done()
+		return
+
+	case OverflowRejectOldest:
+		select {
+		case oldest := <-b.workerChan:
+			log.Printf("batching.dispatch.Overflow: %s, dropping %d keys", policy, len(oldest))
+			b.rejectAll(oldest, ErrOverflow)
+			b.inflight.Done()
+		default:
+		}
+
+	case OverflowMerge:
+		select {
+		case queued := <-b.workerChan:
+			batch = append(queued, batch...)
+			b.inflight.Done()
+		default:
+		}
+	}
+
+	select {
+	case b.workerChan <- batch:
+	case <-ctx.Done():
+		b.leave(batch)
+		b.inflight.Done()
+	case <-b.abortCtx.Done():
+		b.leave(batch)
+		b.inflight.Done()
+	case <-b.stopChan:
+		b.rejectAll(batch, ErrShuttingDown)
+		b.inflight.Done()
+	}
+}
@@ -0,0 +1,121 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// replies collects the results of items by key.
+type replies map[string]chan Result[int]
+
+// newFullBatcher returns a batcher whose worker queue already holds queued.
+func newFullBatcher(policy OverflowPolicy, queued []item[string, int]) *Batcher[string, int] {
+	b := New[string, int](Config{
+		BatchSize:    2,
+		BatchTimeout: time.Second,
+		Workers:      1,
+		Overflow:     policy,
+	}, nil)
+	b.inflight.Add(1)
+	b.workerChan <- queued
+	return b
+}
+
+func (r replies) items(keys ...string) []item[string, int] {
+	items := make([]item[string, int], len(keys))
+	for i, key := range keys {
+		resultChan := make(chan Result[int], 1)
+		r[key] = resultChan
+		items[i] = item[string, int]{key: key, reply: func(result Result[int]) { resultChan <- result }}
+	}
+	return items
+}
+
+func (r replies) assertRejected(t *testing.T, want error, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		select {
+		case result := <-r[key]:
+			if !errors.Is(result.Err, want) {
+				t.Errorf("%s: err = %v, want %v", key, result.Err, want)
+			}
+		default:
+			t.Errorf("%s: not answered", key)
+		}
+	}
+}
+
+func assertQueued(t *testing.T, b *Batcher[string, int], want ...string) {
+	t.Helper()
+	got := batchKeys(<-b.workerChan)
+	if len(got) != len(want) {
+		t.Fatalf("queued batch = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("queued batch = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestDispatchOverflow - политики переполнения очереди воркеров
+func TestDispatchOverflow(t *testing.T) {
+	t.Run("reject newest", func(t *testing.T) {
+		r := replies{}
+		b := newFullBatcher(OverflowRejectNewest, r.items("OLD"))
+		b.dispatch(context.Background(), r.items("NEW"))
+
+		r.assertRejected(t, ErrOverflow, "NEW")
+		assertQueued(t, b, "OLD")
+	})
+
+	t.Run("reject oldest", func(t *testing.T) {
+		r := replies{}
+		b := newFullBatcher(OverflowRejectOldest, r.items("OLD"))
+		b.dispatch(context.Background(), r.items("NEW"))
+
+		r.assertRejected(t, ErrOverflow, "OLD")
+		assertQueued(t, b, "NEW")
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		r := replies{}
+		b := newFullBatcher(OverflowMerge, r.items("OLD1", "OLD2"))
+		b.dispatch(context.Background(), r.items("NEW"))
+
+		assertQueued(t, b, "OLD1", "OLD2", "NEW")
+	})
+
+	t.Run("block", func(t *testing.T) {
+		r := replies{}
+		b := newFullBatcher(OverflowBlock, r.items("OLD"))
+		batch := r.items("NEW")
+		done := make(chan struct{})
+		go func() {
+			b.dispatch(context.Background(), batch)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("dispatch returned while the worker queue was full")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		assertQueued(t, b, "OLD")
+		<-done
+		assertQueued(t, b, "NEW")
+	})
+
+	t.Run("block until shutdown", func(t *testing.T) {
+		r := replies{}
+		b := newFullBatcher(OverflowBlock, r.items("OLD"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		b.dispatch(ctx, r.items("NEW"))
+		r.assertRejected(t, ErrShuttingDown, "NEW")
+	})
+}
@@ -0,0 +1,183 @@
+package batching
+
+import (
+	"sync"
+	"time"
+)
+
+// FlushPolicy decides when a batch is flushed and learns from the outcome.
+// Implementations must be safe for concurrent use.
+type FlushPolicy interface {
+	// BatchSize is the number of keys that triggers a flush.
+	BatchSize() int
+	// Timeout is how long the oldest key of the normal and bulk lanes waits
+	// for a batch to fill.
+	Timeout() time.Duration
+	// ObserveFlush records a flushed batch of batchLen keys that left
+	// backlog keys behind.
+	ObserveFlush(batchLen, backlog int)
+	// ObserveBatch records a batch function call on keys that took latency
+	// and failed for the failed share of them.
+	ObserveBatch(keys int, latency time.Duration, failed float64)
+}
+
+type staticPolicy struct {
+	size    int
+	timeout time.Duration
+}
+
+// StaticPolicy always flushes at size keys or after timeout.
+func StaticPolicy(size int, timeout time.Duration) FlushPolicy {
+	return staticPolicy{size: size, timeout: timeout}
+}
+
+func (p staticPolicy) BatchSize() int                           { return p.size }
+func (p staticPolicy) Timeout() time.Duration                   { return p.timeout }
+func (p staticPolicy) ObserveFlush(batchLen, backlog int)       {}
+func (p staticPolicy) ObserveBatch(int, time.Duration, float64) {}
+
+// ewmaWeight is the share of the newest sample in the latency and error
+// averages.
+const ewmaWeight = 0.3
+
+// AdaptiveConfig bounds an adaptive policy, which starts at Size and Timeout.
+type AdaptiveConfig struct {
+	Size       int
+	Timeout    time.Duration
+	MinSize    int
+	MaxSize    int
+	MinTimeout time.Duration
+	MaxTimeout time.Duration
+	// TargetLatency and MaxErrorRate are the averages above which batches
+	// are made smaller; 10s and 0.2 by default.
+	TargetLatency time.Duration
+	MaxErrorRate  float64
+}
+
+// AdaptivePolicy tunes the batch size and the flush timeout within bounds.
+//
+// The size follows batch health: it grows by a tenth after a healthy call on
+// a full batch and shrinks by a quarter once the average latency exceeds the
+// target or the error rate exceeds the maximum. The timeout follows traffic:
+// it halves while flushes leave a backlog behind and grows by a quarter
+// while flushes find the batch mostly empty or calls fail.
+type AdaptivePolicy struct {
+	minSize       int
+	maxSize       int
+	minTimeout    time.Duration
+	maxTimeout    time.Duration
+	targetLatency time.Duration
+	maxErrorRate  float64
+	metrics       *Metrics
+
+	mu        sync.Mutex
+	size      int
+	timeout   time.Duration
+	latency   time.Duration
+	errorRate float64
+	lastFull  bool
+}
+
+// NewAdaptivePolicy returns a policy publishing its state to the size,
+// timeout, latency and error rate gauges of metrics, if given.
+func NewAdaptivePolicy(cfg AdaptiveConfig, metrics *Metrics) *AdaptivePolicy {
+	p := &AdaptivePolicy{
+		minSize:       cfg.MinSize,
+		maxSize:       cfg.MaxSize,
+		minTimeout:    cfg.MinTimeout,
+		maxTimeout:    cfg.MaxTimeout,
+		targetLatency: cfg.TargetLatency,
+		maxErrorRate:  cfg.MaxErrorRate,
+		metrics:       metrics,
+	}
+	if p.minSize <= 0 {
+		p.minSize = 1
+	}
+	if p.maxSize < p.minSize {
+		p.maxSize = max(cfg.Size, p.minSize)
+	}
+	if p.minTimeout <= 0 {
+		p.minTimeout = cfg.Timeout / 10
+	}
+	if p.maxTimeout < p.minTimeout {
+		p.maxTimeout = max(cfg.Timeout, p.minTimeout)
+	}
+	if p.targetLatency <= 0 {
+		p.targetLatency = 10 * time.Second
+	}
+	if p.maxErrorRate <= 0 {
+		p.maxErrorRate = 0.2
+	}
+
+	p.size = min(max(cfg.Size, p.minSize), p.maxSize)
+	p.timeout = min(max(cfg.Timeout, p.minTimeout), p.maxTimeout)
+	p.publishLocked()
+	return p
+}
+
+func (p *AdaptivePolicy) BatchSize() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+func (p *AdaptivePolicy) Timeout() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.timeout
+}
+
+func (p *AdaptivePolicy) ObserveFlush(batchLen, backlog int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastFull = batchLen >= p.size
+	switch {
+	case backlog > 0:
+		p.timeout = max(p.timeout/2, p.minTimeout)
+	case batchLen*4 < p.size:
+		p.timeout = min(p.timeout*5/4, p.maxTimeout)
+	}
+	p.publishLocked()
+}
+
+func (p *AdaptivePolicy) ObserveBatch(keys int, latency time.Duration, failed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.latency == 0 {
+		p.latency = latency
+	} else {
+		p.latency = time.Duration(ewmaWeight*float64(latency) + (1-ewmaWeight)*float64(p.latency))
+	}
+	p.errorRate = ewmaWeight*failed + (1-ewmaWeight)*p.errorRate
+
+	switch {
+	case p.latency > p.targetLatency || p.errorRate > p.maxErrorRate:
+		p.size = max(p.size*3/4, p.minSize)
+		if p.errorRate > p.maxErrorRate {
+			p.timeout = min(p.timeout*5/4, p.maxTimeout)
+		}
+	case p.lastFull && keys >= p.size:
+		p.size = min(p.size+max(p.size/10, 1), p.maxSize)
+	}
+	p.publishLocked()
+}
+
+func (p *AdaptivePolicy) publishLocked() {
+	if p.metrics == nil {
+		return
+	}
+	if p.metrics.BatchSize != nil {
+		p.metrics.BatchSize.Set(int64(p.size))
+	}
+	if p.metrics.FlushTimeout != nil {
+		p.metrics.FlushTimeout.Set(p.timeout.Milliseconds())
+	}
+	if p.metrics.Latency != nil {
+		p.metrics.Latency.Set(p.latency.Milliseconds())
+	}
+	if p.metrics.ErrorRate != nil {
+		p.metrics.ErrorRate.Set(p.errorRate)
+	}
+}
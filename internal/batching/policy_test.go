@@ -0,0 +1,67 @@
+package batching
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestPolicy() *AdaptivePolicy {
+	return NewAdaptivePolicy(AdaptiveConfig{
+		Size:          20,
+		Timeout:       time.Second,
+		MinSize:       5,
+		MaxSize:       40,
+		MinTimeout:    100 * time.Millisecond,
+		MaxTimeout:    4 * time.Second,
+		TargetLatency: 10 * time.Second,
+		MaxErrorRate:  0.2,
+	}, nil)
+}
+
+// TestAdaptiveSizeGrowsWhenHealthy - быстрые полные батчи увеличивают размер до максимума
+func TestAdaptiveSizeGrowsWhenHealthy(t *testing.T) {
+	p := newTestPolicy()
+	for i := 0; i < 50; i++ {
+		size := p.BatchSize()
+		p.ObserveFlush(size, 0)
+		p.ObserveBatch(size, 2*time.Second, 0)
+	}
+
+	if size := p.BatchSize(); size != 40 {
+		t.Errorf("size = %d, want the maximum 40", size)
+	}
+}
+
+// TestAdaptiveSizeShrinks - медленные или неудачные батчи уменьшают размер до минимума
+func TestAdaptiveSizeShrinks(t *testing.T) {
+	for name, observe := range map[string]func(p *AdaptivePolicy){
+		"latency": func(p *AdaptivePolicy) { p.ObserveBatch(20, 30*time.Second, 0) },
+		"errors":  func(p *AdaptivePolicy) { p.ObserveBatch(20, time.Second, 1) },
+	} {
+		p := newTestPolicy()
+		for i := 0; i < 20; i++ {
+			observe(p)
+		}
+		if size := p.BatchSize(); size != 5 {
+			t.Errorf("%s: size = %d, want the minimum 5", name, size)
+		}
+	}
+}
+
+// TestAdaptiveTimeout - очередь сокращает таймаут, редкие запросы его увеличивают
+func TestAdaptiveTimeout(t *testing.T) {
+	p := newTestPolicy()
+	for i := 0; i < 10; i++ {
+		p.ObserveFlush(20, 100)
+	}
+	if timeout := p.Timeout(); timeout != 100*time.Millisecond {
+		t.Errorf("timeout under backlog = %s, want the minimum 100ms", timeout)
+	}
+
+	for i := 0; i < 30; i++ {
+		p.ObserveFlush(1, 0)
+	}
+	if timeout := p.Timeout(); timeout != 4*time.Second {
+		t.Errorf("timeout with sparse traffic = %s, want the maximum 4s", timeout)
+	}
+}
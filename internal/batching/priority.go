@@ -0,0 +1,67 @@
+package batching
+
+import (
+	"fmt"
+	"time"
+)
+
+// Priority selects the lane a key waits in.
+type Priority int
+
+const (
+	// PriorityInteractive is for callers waiting on a page.
+	PriorityInteractive Priority = iota
+	PriorityNormal
+	// PriorityBulk is for background work.
+	PriorityBulk
+)
+
+const laneCount = 3
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityNormal:
+		return "normal"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// ParsePriority accepts the names returned by String.
+func ParsePriority(name string) (Priority, error) {
+	for p := PriorityInteractive; p < laneCount; p++ {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority: %q", name)
+}
+
+func (p Priority) valid() bool {
+	return p >= PriorityInteractive && p < laneCount
+}
+
+func (b *Batcher[K, V]) laneWeight(p Priority) int {
+	switch p {
+	case PriorityInteractive:
+		return b.config.InteractiveWeight
+	case PriorityNormal:
+		return b.config.NormalWeight
+	default:
+		return b.config.BulkWeight
+	}
+}
+
+// laneTimeout is the flush timeout of lane p. Interactive items never wait
+// longer than the others.
+func (b *Batcher[K, V]) laneTimeout(p Priority) time.Duration {
+	timeout := b.config.Policy.Timeout()
+	if p == PriorityInteractive {
+		return min(b.config.InteractiveTimeout, timeout)
+	}
+	return timeout
+}
@@ -0,0 +1,84 @@
+package batching
+
+import (
+	"testing"
+	"time"
+)
+
+func newLaneBatcher(size int) *Batcher[string, int] {
+	return New[string, int](Config{
+		BatchSize:         size,
+		BatchTimeout:      time.Second,
+		StarvationTimeout: time.Minute,
+		InteractiveWeight: 2,
+		NormalWeight:      1,
+		BulkWeight:        1,
+	}, nil)
+}
+
+func (b *Batcher[K, V]) fillLane(p Priority, keys []K, enqueuedAt time.Time) {
+	for _, key := range keys {
+		b.lanes[p] = append(b.lanes[p], item[K, V]{key: key, priority: p, enqueuedAt: enqueuedAt})
+	}
+}
+
+func batchKeys[K comparable, V any](items []item[K, V]) []K {
+	keys := make([]K, len(items))
+	for i, it := range items {
+		keys[i] = it.key
+	}
+	return keys
+}
+
+// TestTakeBatchWeighted - взвешенная выборка из очередей приоритетов
+func TestTakeBatchWeighted(t *testing.T) {
+	b := newLaneBatcher(6)
+	now := time.Now()
+	b.fillLane(PriorityBulk, []string{"B1", "B2", "B3"}, now)
+	b.fillLane(PriorityNormal, []string{"N1", "N2"}, now)
+	b.fillLane(PriorityInteractive, []string{"I1", "I2", "I3"}, now)
+
+	got := batchKeys(b.takeBatchLocked())
+	want := []string{"I1", "I2", "N1", "B1", "I3", "N2"}
+	if len(got) != len(want) {
+		t.Fatalf("batch = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("batch = %v, want %v", got, want)
+		}
+	}
+
+	if rest := batchKeys(b.takeBatchLocked()); len(rest) != 2 || rest[0] != "B2" || rest[1] != "B3" {
+		t.Errorf("second batch = %v, want [B2 B3]", rest)
+	}
+	if b.pendingLocked() != 0 {
+		t.Errorf("pending = %d, want 0", b.pendingLocked())
+	}
+}
+
+// TestTakeBatchStarvation - давно ожидающие bulk-ключи идут первыми
+func TestTakeBatchStarvation(t *testing.T) {
+	b := newLaneBatcher(2)
+	now := time.Now()
+	b.fillLane(PriorityBulk, []string{"OLD"}, now.Add(-2*time.Minute))
+	b.fillLane(PriorityInteractive, []string{"I1", "I2", "I3"}, now)
+
+	got := batchKeys(b.takeBatchLocked())
+	if len(got) != 2 || got[0] != "OLD" || got[1] != "I1" {
+		t.Errorf("batch = %v, want [OLD I1]", got)
+	}
+}
+
+// TestParsePriority - разбор названий приоритетов
+func TestParsePriority(t *testing.T) {
+	for _, p := range []Priority{PriorityInteractive, PriorityNormal, PriorityBulk} {
+		got, err := ParsePriority(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParsePriority(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Errorf("ParsePriority(urgent) succeeded")
+	}
+}
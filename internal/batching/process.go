@@ -0,0 +1,201 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// process calls the batch function on the keys of items under the batch
+// deadline. Keys whose callers have all left are dropped before the call,
+// and the call is cancelled once every caller has left. Results reach the
+// callers as the batch function delivers them.
+func (b *Batcher[K, V]) process(items []item[K, V]) {
+	if len(items) == 0 {
+		return
+	}
+
+	pending, keys := b.liveKeys(items)
+	if len(keys) == 0 {
+		log.Printf("batching.process.Abandoned: all %d callers left", len(items))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(b.abortCtx, b.config.BatchDeadline)
+	defer cancel()
+	watch := b.watchAbandoned(pending, cancel)
+	defer watch.release()
+
+	d := &delivery[K, V]{pending: pending, watch: watch}
+	start := b.config.Clock.Now()
+	err := b.fn(ctx, keys, d.deliver)
+	latency := b.config.Clock.Since(start)
+
+	rest, restKeys := d.takeAll()
+	if b.abortCtx.Err() != nil {
+		b.leave(rest)
+		return
+	}
+
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		failed := d.failedKeys()
+		if err != nil {
+			failed += restKeys
+		}
+		b.config.Policy.ObserveBatch(len(keys), latency, float64(failed)/float64(len(keys)))
+	}
+
+	if err == nil {
+		b.markSuccess()
+		err = ErrNotFound
+	}
+	for _, it := range rest {
+		it.reply(Result[V]{Err: err})
+	}
+}
+
+// liveKeys groups items by key, answers callers that already left and
+// returns the keys somebody still waits for, in batch order.
+func (b *Batcher[K, V]) liveKeys(items []item[K, V]) (map[K][]item[K, V], []K) {
+	pending := make(map[K][]item[K, V], len(items))
+	var keys []K
+	cancelled := 0
+	for _, it := range items {
+		if it.ctx != nil && it.ctx.Err() != nil {
+			it.reply(Result[V]{Err: &RejectedError{Err: ErrCancelled}})
+			cancelled++
+			continue
+		}
+		if _, seen := pending[it.key]; !seen {
+			keys = append(keys, it.key)
+		}
+		pending[it.key] = append(pending[it.key], it)
+	}
+
+	if cancelled > 0 {
+		addTo(b.config.Metrics.Cancelled, "before_scrape", int64(cancelled))
+	}
+	return pending, keys
+}
+
+// abandonWatch cancels a batch once every caller still waiting for a result
+// has left. Callers that already got their result no longer count.
+type abandonWatch[K comparable] struct {
+	mu        sync.Mutex
+	stops     map[K][]func() bool
+	remaining atomic.Int64
+}
+
+// watchAbandoned calls cancel once the contexts of all pending items are done.
+// Items without a context keep the batch alive, in which case it returns nil.
+func (b *Batcher[K, V]) watchAbandoned(pending map[K][]item[K, V], cancel context.CancelFunc) *abandonWatch[K] {
+	for _, items := range pending {
+		for _, it := range items {
+			if it.ctx == nil || it.ctx.Done() == nil {
+				return nil
+			}
+		}
+	}
+
+	w := &abandonWatch[K]{stops: make(map[K][]func() bool, len(pending))}
+	for _, items := range pending {
+		w.remaining.Add(int64(len(items)))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, items := range pending {
+		for _, it := range items {
+			w.stops[key] = append(w.stops[key], context.AfterFunc(it.ctx, func() {
+				if w.remaining.Add(-1) == 0 {
+					addTo(b.config.Metrics.Cancelled, "during_scrape", 1)
+					cancel()
+				}
+			}))
+		}
+	}
+	return w
+}
+
+// settle drops the callers of key from the live count once their result is
+// delivered. Callers that left earlier were already counted out.
+func (w *abandonWatch[K]) settle(key K) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	stops := w.stops[key]
+	delete(w.stops, key)
+	w.mu.Unlock()
+
+	for _, stop := range stops {
+		if stop() {
+			w.remaining.Add(-1)
+		}
+	}
+}
+
+// release stops every watcher still registered.
+func (w *abandonWatch[K]) release() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, stops := range w.stops {
+		for _, stop := range stops {
+			stop()
+		}
+		delete(w.stops, key)
+	}
+}
+
+// delivery hands results to the callers waiting for them, each key once.
+type delivery[K comparable, V any] struct {
+	mu      sync.Mutex
+	pending map[K][]item[K, V]
+	watch   *abandonWatch[K]
+	failed  int
+}
+
+func (d *delivery[K, V]) deliver(key K, value V, err error) {
+	d.mu.Lock()
+	items, ok := d.pending[key]
+	delete(d.pending, key)
+	if ok && err != nil {
+		d.failed++
+	}
+	d.mu.Unlock()
+
+	if ok {
+		d.watch.settle(key)
+	}
+	for _, it := range items {
+		it.reply(Result[V]{Value: value, Err: err})
+	}
+}
+
+func (d *delivery[K, V]) failedKeys() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.failed
+}
+
+// takeAll removes and returns every item still waiting and the number of
+// their keys.
+func (d *delivery[K, V]) takeAll() ([]item[K, V], int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var items []item[K, V]
+	keys := len(d.pending)
+	for key, waiting := range d.pending {
+		items = append(items, waiting...)
+		delete(d.pending, key)
+	}
+	return items, keys
+}
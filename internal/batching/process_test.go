@@ -0,0 +1,230 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a batch function that remembers the keys it was called with
+// and handles each key with handle.
+type recorder struct {
+	mu     sync.Mutex
+	keys   []string
+	handle func(ctx context.Context, key string) (int, error)
+}
+
+func (r *recorder) batch(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+	r.mu.Lock()
+	r.keys = append(r.keys, keys...)
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var batchErr error
+	var errMu sync.Mutex
+	for _, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := r.handle(ctx, key)
+			if err != nil {
+				errMu.Lock()
+				batchErr = err
+				errMu.Unlock()
+				return
+			}
+			deliver(key, value, nil)
+		}()
+	}
+	wg.Wait()
+	return batchErr
+}
+
+func found(ctx context.Context, key string) (int, error) {
+	return len(key), nil
+}
+
+func newProcessBatcher(r *recorder, deadline time.Duration) *Batcher[string, int] {
+	return New(Config{
+		BatchSize:     10,
+		BatchTimeout:  time.Second,
+		BatchDeadline: deadline,
+	}, r.batch)
+}
+
+func itemWithContext(ctx context.Context, key string) (item[string, int], <-chan Result[int]) {
+	resultChan := make(chan Result[int], 1)
+	return item[string, int]{ctx: ctx, key: key, reply: func(result Result[int]) { resultChan <- result }}, resultChan
+}
+
+// TestProcessSkipsCancelled - ключи, чьи клиенты ушли, не обрабатываются
+func TestProcessSkipsCancelled(t *testing.T) {
+	r := &recorder{handle: found}
+	b := newProcessBatcher(r, time.Second)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	gone, goneResult := itemWithContext(cancelled, "GONE")
+	shared, _ := itemWithContext(cancelled, "SHARED")
+	waiting, waitingResult := itemWithContext(context.Background(), "SHARED")
+	b.process([]item[string, int]{gone, shared, waiting})
+
+	if len(r.keys) != 1 || r.keys[0] != "SHARED" {
+		t.Errorf("keys = %v, want [SHARED]", r.keys)
+	}
+	if result := <-goneResult; !errors.Is(result.Err, ErrCancelled) {
+		t.Errorf("cancelled caller got %+v", result)
+	}
+	if result := <-waitingResult; result.Err != nil || result.Value != 6 {
+		t.Errorf("waiting caller got %+v", result)
+	}
+}
+
+// TestProcessFansOut - все клиенты одного ключа получают один результат
+func TestProcessFansOut(t *testing.T) {
+	r := &recorder{handle: found}
+	b := newProcessBatcher(r, time.Second)
+
+	first, firstResult := itemWithContext(context.Background(), "KEY")
+	second, secondResult := itemWithContext(context.Background(), "KEY")
+	b.process([]item[string, int]{first, second})
+
+	if len(r.keys) != 1 {
+		t.Errorf("keys = %v, want [KEY]", r.keys)
+	}
+	for _, resultChan := range []<-chan Result[int]{firstResult, secondResult} {
+		if result := <-resultChan; result.Err != nil || result.Value != 3 {
+			t.Errorf("caller got %+v", result)
+		}
+	}
+}
+
+// TestProcessNotFound - недоставленные ключи получают ErrNotFound
+func TestProcessNotFound(t *testing.T) {
+	b := New(Config{BatchSize: 10}, func(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+		return nil
+	})
+
+	it, result := itemWithContext(context.Background(), "NOWHERE")
+	b.process([]item[string, int]{it})
+
+	if got := <-result; !errors.Is(got.Err, ErrNotFound) {
+		t.Errorf("got %+v, want ErrNotFound", got)
+	}
+}
+
+// TestProcessStreamsResults - результат приходит до окончания батча
+func TestProcessStreamsResults(t *testing.T) {
+	release := make(chan struct{})
+	r := &recorder{handle: func(ctx context.Context, key string) (int, error) {
+		if key == "SLOW" {
+			<-release
+		}
+		return found(ctx, key)
+	}}
+	b := newProcessBatcher(r, time.Second)
+
+	fast, fastResult := itemWithContext(context.Background(), "FAST")
+	slow, slowResult := itemWithContext(context.Background(), "SLOW")
+	done := make(chan struct{})
+	go func() {
+		b.process([]item[string, int]{fast, slow})
+		close(done)
+	}()
+
+	select {
+	case result := <-fastResult:
+		if result.Err != nil {
+			t.Errorf("fast key got %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast key waited for the whole batch")
+	}
+
+	close(release)
+	<-done
+	if result := <-slowResult; result.Err != nil {
+		t.Errorf("slow key got %+v", result)
+	}
+}
+
+// TestProcessDeadline - зависшая обработка прерывается по дедлайну батча
+func TestProcessDeadline(t *testing.T) {
+	r := &recorder{handle: func(ctx context.Context, key string) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}}
+	b := newProcessBatcher(r, 20*time.Millisecond)
+
+	it, result := itemWithContext(context.Background(), "HUNG")
+	b.process([]item[string, int]{it})
+
+	if got := <-result; !errors.Is(got.Err, context.DeadlineExceeded) {
+		t.Errorf("got %+v, want deadline exceeded", got)
+	}
+}
+
+// TestProcessAbandoned - обработка отменяется, когда ушли все клиенты
+func TestProcessAbandoned(t *testing.T) {
+	started := make(chan struct{})
+	r := &recorder{handle: func(ctx context.Context, key string) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}}
+	b := newProcessBatcher(r, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it, _ := itemWithContext(ctx, "LEFT")
+	done := make(chan struct{})
+	go func() {
+		b.process([]item[string, int]{it})
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("batch kept running after every caller left")
+	}
+}
+
+// TestProcessDeliveredNotAbandoned - клиент, уже получивший результат, не отменяет батч уходом
+func TestProcessDeliveredNotAbandoned(t *testing.T) {
+	cancelled := new(expvar.Map).Init()
+	b := New(Config{BatchSize: 10, BatchDeadline: time.Minute, Metrics: &Metrics{Cancelled: cancelled}},
+		func(ctx context.Context, keys []string, deliver func(string, int, error)) error {
+			deliver(keys[0], 1, nil)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+				return nil
+			}
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it, result := itemWithContext(ctx, "DONE")
+	done := make(chan struct{})
+	go func() {
+		b.process([]item[string, int]{it})
+		close(done)
+	}()
+
+	if got := <-result; got.Err != nil || got.Value != 1 {
+		t.Errorf("caller got %+v", got)
+	}
+	cancel()
+	<-done
+
+	if v := cancelled.Get("during_scrape"); v != nil {
+		t.Errorf("during_scrape = %s, want the delivered batch left alone", v)
+	}
+}
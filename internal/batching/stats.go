@@ -0,0 +1,118 @@
+package batching
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stats is a snapshot of a Batcher, taken without queueing anything.
+type Stats struct {
+	// Queued is the number of keys not yet handed to a worker, Lanes the
+	// same by lane.
+	Queued int            `json:"queued"`
+	Lanes  map[string]int `json:"lanes"`
+	// Incoming is the part of Queued the main loop has not picked up yet;
+	// it grows when the loop falls behind.
+	Incoming int `json:"incoming"`
+	// QueuedBatches is the number of batches waiting for a free worker.
+	QueuedBatches int `json:"queued_batches"`
+	// OldestPendingSeconds is how long the oldest key in the lanes has been
+	// waiting for its batch.
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"`
+
+	Workers       int `json:"workers"`
+	ActiveWorkers int `json:"active_workers"`
+	// StuckWorkers are busy with a batch for longer than its deadline and
+	// the abort grace, which means the batch function ignores ctx.
+	StuckWorkers int `json:"stuck_workers"`
+
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	ShuttingDown  bool       `json:"shutting_down,omitempty"`
+}
+
+// Stats reports the queue depth, the age of the oldest pending key and the
+// state of the workers.
+func (b *Batcher[K, V]) Stats() Stats {
+	now := b.config.Clock.Now()
+	stats := Stats{
+		Lanes:         make(map[string]int, laneCount),
+		QueuedBatches: len(b.workerChan),
+		Workers:       b.config.Workers,
+		ShuttingDown:  b.isClosing(),
+	}
+
+	b.mu.Lock()
+	for p := range b.lanes {
+		incoming := len(b.inputChans[p])
+		queued := len(b.lanes[p]) + incoming
+		stats.Lanes[Priority(p).String()] = queued
+		stats.Queued += queued
+		stats.Incoming += incoming
+		if len(b.lanes[p]) > 0 {
+			age := now.Sub(b.lanes[p][0].enqueuedAt).Seconds()
+			stats.OldestPendingSeconds = max(stats.OldestPendingSeconds, age)
+		}
+	}
+	b.mu.Unlock()
+
+	stuckAfter := b.config.BatchDeadline + abortGrace
+	b.statsMu.Lock()
+	for _, since := range b.busySince {
+		if since.IsZero() {
+			continue
+		}
+		stats.ActiveWorkers++
+		if now.Sub(since) > stuckAfter {
+			stats.StuckWorkers++
+		}
+	}
+	if !b.lastSuccess.IsZero() {
+		lastSuccess := b.lastSuccess
+		stats.LastSuccessAt = &lastSuccess
+	}
+	b.statsMu.Unlock()
+
+	return stats
+}
+
+// Health reports whether the batcher can still serve keys: it is not
+// shutting down and at least one worker is not stuck. It only reads the
+// state of the batcher.
+func (b *Batcher[K, V]) Health(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	stats := b.Stats()
+	if stats.ShuttingDown {
+		return ErrShuttingDown
+	}
+	if stats.StuckWorkers >= stats.Workers {
+		return fmt.Errorf("all %d workers stuck", stats.Workers)
+	}
+	return nil
+}
+
+func (b *Batcher[K, V]) isClosing() bool {
+	select {
+	case <-b.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// setBusy records when worker started its batch; a zero since marks it
+// idle.
+func (b *Batcher[K, V]) setBusy(worker int, since time.Time) {
+	b.statsMu.Lock()
+	b.busySince[worker] = since
+	b.statsMu.Unlock()
+}
+
+func (b *Batcher[K, V]) markSuccess() {
+	b.statsMu.Lock()
+	b.lastSuccess = b.config.Clock.Now()
+	b.statsMu.Unlock()
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type batchIDKey struct{}
+
+type chunkIndexKey struct{}
+
+// WithBatchID tags ctx with the id of the batch being tracked. Clients file
+// snapshots and log lines of every page of the batch under it.
+func WithBatchID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, id)
+}
+
+// BatchID returns the batch id of ctx and the index of the chunk RunChunks is
+// tracking with it. The id is empty outside a batch.
+func BatchID(ctx context.Context) (id string, chunk int) {
+	id, _ = ctx.Value(batchIDKey{}).(string)
+	chunk, _ = ctx.Value(chunkIndexKey{}).(int)
+	return id, chunk
+}
+
+// NewBatchID returns a random 16-character hex id.
+func NewBatchID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
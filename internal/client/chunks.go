@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+type ChunkFunc func(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error)
+
+// ResultSink receives the codes of every chunk as soon as the chunk is
+// parsed, before the whole batch is done. It may be called concurrently.
+type ResultSink func(results map[string]*models.TrackData)
+
+type resultSinkKey struct{}
+
+// WithResultSink asks RunChunks to report chunk results to sink as they
+// arrive. Clients that do not chunk never call it.
+func WithResultSink(ctx context.Context, sink ResultSink) context.Context {
+	return context.WithValue(ctx, resultSinkKey{}, sink)
+}
+
+func emitResults(ctx context.Context, results map[string]*models.TrackData) {
+	if sink, ok := ctx.Value(resultSinkKey{}).(ResultSink); ok && len(results) > 0 {
+		sink(results)
+	}
+}
+
+// PageGate is called before each page is scraped and returns the function to
+// call once the page is done. An error skips the page.
+type PageGate func(ctx context.Context) (release func(), err error)
+
+type pageGateKey struct{}
+
+// WithPageGate makes RunChunks pass every chunk through gate.
+func WithPageGate(ctx context.Context, gate PageGate) context.Context {
+	return context.WithValue(ctx, pageGateKey{}, gate)
+}
+
+// runChunk tracks chunk with fn once the PageGate of ctx, if any, lets it
+// through. The context fn gets carries the index of the chunk.
+func runChunk(ctx context.Context, index int, chunk []string, fn ChunkFunc) (map[string]*models.TrackData, error) {
+	ctx = context.WithValue(ctx, chunkIndexKey{}, index)
+	if gate, ok := ctx.Value(pageGateKey{}).(PageGate); ok {
+		release, err := gate(ctx)
+		if err != nil {
+			return nil, erors.NewClientError("request timeout", erors.ErrRequestTimeout)
+		}
+		defer release()
+	}
+
+	return fn(ctx, chunk)
+}
+
+// RunChunks tracks every chunk with fn, at most concurrency at a time, and
+// merges the results. Each chunk waits for the PageGate of ctx and its
+// results also go to the ResultSink of ctx, if any. Chunks share the batch id
+// of ctx, or a new one when ctx has none. When every chunk fails
+// the first error is returned as is; otherwise failures of individual chunks
+// are attributed to their codes through an erors.PartialBatchError returned
+// alongside the merged results.
+func RunChunks(ctx context.Context, chunks [][]string, concurrency int, fn ChunkFunc) (map[string]*models.TrackData, error) {
+	if id, _ := BatchID(ctx); id == "" {
+		ctx = WithBatchID(ctx, NewBatchID())
+	}
+
+	if len(chunks) == 1 {
+		results, err := runChunk(ctx, 0, chunks[0], fn)
+		emitResults(ctx, results)
+		return results, err
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make(map[string]*models.TrackData)
+		failed   = make(map[string]error)
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for index, chunk := range chunks {
+		wg.Add(1)
+		go func(index int, chunk []string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				recordChunkFailure(failed, &firstErr, chunk, erors.NewClientError("request timeout", erors.ErrRequestTimeout))
+				mu.Unlock()
+				return
+			}
+
+			chunkResults, err := runChunk(ctx, index, chunk, fn)
+			emitResults(ctx, chunkResults)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for code, data := range chunkResults {
+				results[code] = data
+			}
+			if partialErr, ok := erors.AsPartialBatch(err); ok {
+				for code, codeErr := range partialErr.Failed {
+					failed[code] = codeErr
+				}
+			} else if err != nil {
+				recordChunkFailure(failed, &firstErr, chunk, err)
+			}
+		}(index, chunk)
+	}
+	wg.Wait()
+
+	if len(results) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	if len(failed) > 0 {
+		return results, &erors.PartialBatchError{Failed: failed}
+	}
+
+	return results, nil
+}
+
+func recordChunkFailure(failed map[string]error, firstErr *error, chunk []string, err error) {
+	if *firstErr == nil {
+		*firstErr = err
+	}
+	for _, code := range chunk {
+		failed[code] = err
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+func TestRunChunksResultSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		streamed []string
+	)
+	ctx := WithResultSink(context.Background(), func(results map[string]*models.TrackData) {
+		mu.Lock()
+		defer mu.Unlock()
+		for code := range results {
+			streamed = append(streamed, code)
+		}
+	})
+
+	fn := func(ctx context.Context, codes []string) (map[string]*models.TrackData, error) {
+		results := make(map[string]*models.TrackData)
+		for _, code := range codes {
+			results[code] = &models.TrackData{}
+		}
+		return results, nil
+	}
+
+	results, err := RunChunks(ctx, [][]string{{"A", "B"}, {"C"}}, 2, fn)
+	if err != nil {
+		t.Fatalf("RunChunks: %v", err)
+	}
+	if len(results) != 3 || len(streamed) != 3 {
+		t.Errorf("results = %d, streamed = %v, want 3 each", len(results), streamed)
+	}
+
+	if _, err := RunChunks(context.Background(), [][]string{{"A"}}, 1, fn); err != nil {
+		t.Errorf("RunChunks without sink: %v", err)
+	}
+}
+
+func TestRunChunksPageGate(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		acquired int
+		released int
+	)
+	ctx := WithPageGate(context.Background(), func(ctx context.Context) (func(), error) {
+		mu.Lock()
+		defer mu.Unlock()
+		acquired++
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			released++
+		}, nil
+	})
+
+	fn := func(ctx context.Context, codes []string) (map[string]*models.TrackData, error) {
+		return map[string]*models.TrackData{codes[0]: {}}, nil
+	}
+
+	if _, err := RunChunks(ctx, [][]string{{"A"}, {"B"}, {"C"}}, 2, fn); err != nil {
+		t.Fatalf("RunChunks: %v", err)
+	}
+	if acquired != 3 || released != 3 {
+		t.Errorf("acquired = %d, released = %d, want 3 each", acquired, released)
+	}
+
+	closed := WithPageGate(context.Background(), func(ctx context.Context) (func(), error) {
+		return nil, context.DeadlineExceeded
+	})
+	if _, err := RunChunks(closed, [][]string{{"A"}}, 1, fn); err == nil {
+		t.Errorf("RunChunks succeeded with a closed gate")
+	}
+}
+
+func TestRunChunksBatchID(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		seen   = make(map[string]string)
+		chunks = make(map[string]int)
+	)
+	fn := func(ctx context.Context, codes []string) (map[string]*models.TrackData, error) {
+		id, chunk := BatchID(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		seen[codes[0]] = id
+		chunks[codes[0]] = chunk
+		return nil, nil
+	}
+
+	ctx := WithBatchID(context.Background(), "batch-1")
+	RunChunks(ctx, [][]string{{"A"}, {"B"}, {"C"}}, 2, fn)
+	for code, want := range map[string]int{"A": 0, "B": 1, "C": 2} {
+		if seen[code] != "batch-1" || chunks[code] != want {
+			t.Errorf("chunk %s ran with batch %q chunk %d, want batch-1 chunk %d", code, seen[code], chunks[code], want)
+		}
+	}
+
+	RunChunks(context.Background(), [][]string{{"A"}, {"B"}}, 2, fn)
+	if seen["A"] == "" || seen["A"] != seen["B"] {
+		t.Errorf("chunks without a batch id got %q and %q, want one shared new id", seen["A"], seen["B"])
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+)
+
+// LayoutSignals are structural counters collected while parsing one scraped
+// page. They show whether the page still looks the way the parser expects.
+type LayoutSignals struct {
+	CodesRequested int `json:"codes_requested"`
+	ListItems      int `json:"list_items"`
+	MatchedCodes   int `json:"matched_codes"`
+	TimelineItems  int `json:"timeline_items"`
+	DateMatches    int `json:"date_matches"`
+}
+
+// Empty reports whether the page produced nothing usable: none of the
+// requested codes were found or no timeline entry had a recognisable date.
+func (s LayoutSignals) Empty() bool {
+	return s.MatchedCodes == 0 || s.DateMatches == 0
+}
+
+type DriftStatus struct {
+	Alarm       bool           `json:"alarm"`
+	EmptyRatio  float64        `json:"empty_ratio"`
+	Samples     int            `json:"samples"`
+	Window      int            `json:"window"`
+	Threshold   float64        `json:"threshold"`
+	AlarmSince  *time.Time     `json:"alarm_since,omitempty"`
+	LastSignals *LayoutSignals `json:"last_signals,omitempty"`
+}
+
+// DriftDetector keeps the last window parse outcomes and raises an alarm when
+// the share of empty parses reaches threshold, once at least minSamples
+// batches have been observed.
+type DriftDetector struct {
+	provider   string
+	window     int
+	threshold  float64
+	minSamples int
+
+	mu         sync.Mutex
+	outcomes   []bool
+	next       int
+	filled     int
+	empty      int
+	alarmSince time.Time
+	last       *LayoutSignals
+}
+
+func NewDriftDetector(provider string, window int, threshold float64, minSamples int) *DriftDetector {
+	if window <= 0 {
+		window = 1
+	}
+
+	return &DriftDetector{
+		provider:   provider,
+		window:     window,
+		threshold:  threshold,
+		minSamples: minSamples,
+		outcomes:   make([]bool, window),
+	}
+}
+
+func (d *DriftDetector) Observe(signals LayoutSignals) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	empty := signals.Empty()
+	if empty {
+		metrics.LayoutEmptyParses.Add(1)
+	}
+
+	if d.filled == d.window {
+		if d.outcomes[d.next] {
+			d.empty--
+		}
+	} else {
+		d.filled++
+	}
+	d.outcomes[d.next] = empty
+	if empty {
+		d.empty++
+	}
+	d.next = (d.next + 1) % d.window
+	d.last = &signals
+
+	ratio := float64(d.empty) / float64(d.filled)
+	alarm := d.filled >= d.minSamples && ratio >= d.threshold
+
+	switch {
+	case alarm && d.alarmSince.IsZero():
+		d.alarmSince = time.Now()
+		metrics.LayoutDriftAlarm.Set(1)
+		log.Printf("client.DriftDetector.Alarm: %s layout drift suspected, %.0f%% of last %d parses empty (last: %+v)",
+			d.provider, ratio*100, d.filled, signals)
+	case !alarm && !d.alarmSince.IsZero():
+		d.alarmSince = time.Time{}
+		metrics.LayoutDriftAlarm.Set(0)
+		log.Printf("client.DriftDetector.Recovered: %s empty parse ratio back to %.0f%%", d.provider, ratio*100)
+	}
+}
+
+func (d *DriftDetector) Status() DriftStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := DriftStatus{
+		Samples:   d.filled,
+		Window:    d.window,
+		Threshold: d.threshold,
+	}
+	if d.filled > 0 {
+		status.EmptyRatio = float64(d.empty) / float64(d.filled)
+	}
+	if !d.alarmSince.IsZero() {
+		since := d.alarmSince
+		status.Alarm = true
+		status.AlarmSince = &since
+	}
+	if d.last != nil {
+		last := *d.last
+		status.LastSignals = &last
+	}
+
+	return status
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+)
+
+var (
+	goodParse  = LayoutSignals{CodesRequested: 1, ListItems: 1, MatchedCodes: 1, TimelineItems: 3, DateMatches: 3}
+	emptyParse = LayoutSignals{CodesRequested: 1}
+)
+
+// TestDriftDetector - скользящее окно, порог тревоги, восстановление и метрики
+func TestDriftDetector(t *testing.T) {
+	d := NewDriftDetector("test", 4, 0.5, 3)
+	emptyBefore := metrics.LayoutEmptyParses.Value()
+
+	steps := []struct {
+		signals LayoutSignals
+		alarm   bool
+		ratio   float64
+		samples int
+	}{
+		{emptyParse, false, 1, 1},     // below minSamples
+		{emptyParse, false, 1, 2},     // still below minSamples
+		{goodParse, true, 2.0 / 3, 3}, // minSamples reached, 2/3 empty
+		{goodParse, true, 0.5, 4},     // window full, 2/4 is exactly the threshold
+		{goodParse, false, 0.25, 4},   // the first empty parse slides out
+		{goodParse, false, 0, 4},      // the second one too
+		{emptyParse, false, 0.25, 4},  // one empty parse in the window
+		{emptyParse, true, 0.5, 4},    // back at the threshold
+	}
+
+	for i, step := range steps {
+		d.Observe(step.signals)
+		status := d.Status()
+
+		if status.Alarm != step.alarm || status.EmptyRatio != step.ratio || status.Samples != step.samples {
+			t.Fatalf("step %d: status = %+v, want alarm %v, ratio %v, samples %d",
+				i+1, status, step.alarm, step.ratio, step.samples)
+		}
+		if status.Alarm != (status.AlarmSince != nil) {
+			t.Errorf("step %d: alarm %v with AlarmSince %v", i+1, status.Alarm, status.AlarmSince)
+		}
+		wantMetric := int64(0)
+		if step.alarm {
+			wantMetric = 1
+		}
+		if got := metrics.LayoutDriftAlarm.Value(); got != wantMetric {
+			t.Errorf("step %d: layout_drift_alarm = %d, want %d", i+1, got, wantMetric)
+		}
+		if status.LastSignals == nil || *status.LastSignals != step.signals {
+			t.Errorf("step %d: last signals = %+v", i+1, status.LastSignals)
+		}
+	}
+
+	if got := metrics.LayoutEmptyParses.Value() - emptyBefore; got != 4 {
+		t.Errorf("layout_empty_parses grew by %d, want 4", got)
+	}
+}
+
+// TestDriftDetectorAlarmSinceKept - время начала тревоги не сдвигается, пока тревога длится
+func TestDriftDetectorAlarmSinceKept(t *testing.T) {
+	d := NewDriftDetector("test", 2, 1, 1)
+
+	d.Observe(emptyParse)
+	first := d.Status().AlarmSince
+	if first == nil {
+		t.Fatal("no alarm after an empty parse with threshold 1")
+	}
+
+	d.Observe(emptyParse)
+	if again := d.Status().AlarmSince; again == nil || !again.Equal(*first) {
+		t.Errorf("AlarmSince moved from %v to %v", first, again)
+	}
+
+	d.Observe(goodParse)
+	if status := d.Status(); status.Alarm || status.AlarmSince != nil {
+		t.Errorf("status after a good parse = %+v, want recovered", status)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// EventRule assigns Code to descriptions containing any of Keywords
+// (case-insensitive). Keywords match whole words, so "signed" does not match
+// "assigned"; a trailing "*" also matches longer words, as "return*" matches
+// "returned".
+type EventRule struct {
+	Code     string
+	Keywords []string
+}
+
+// EventRules describe how one provider words its events. Codes are checked in
+// order and the first match wins, so specific rules go before generic ones.
+type EventRules struct {
+	Codes []EventRule
+	// Placeholders are facility names the provider shows when no real
+	// facility is known, e.g. "SYSTEM".
+	Placeholders []string
+}
+
+// Classify returns the normalized event code for a description.
+func (r EventRules) Classify(description string) string {
+	text := strings.ToLower(description)
+	for _, rule := range r.Codes {
+		for _, keyword := range rule.Keywords {
+			if containsWords(text, keyword) {
+				return rule.Code
+			}
+		}
+	}
+	return models.EventUnknown
+}
+
+// containsWords reports whether keyword occurs in text with a word boundary
+// on both sides, or only before it when keyword ends with "*".
+func containsWords(text, keyword string) bool {
+	prefix := strings.HasSuffix(keyword, "*")
+	keyword = strings.TrimSuffix(keyword, "*")
+
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(keyword)
+		if !isWordByte(text, start-1) && (prefix || !isWordByte(text, end)) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// IsPlaceholder reports whether facility carries no information.
+func (r EventRules) IsPlaceholder(facility string) bool {
+	for _, placeholder := range r.Placeholders {
+		if strings.EqualFold(facility, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderEvents deduplicates data.Events by (instant, normalized status text),
+// sorts them newest first and sets FirstEvent and LastEvent. Events whose date
+// could not be parsed keep their page order after the dated ones.
+func OrderEvents(data *models.TrackData) {
+	type keyedEvent struct {
+		event  models.Event
+		at     time.Time
+		parsed bool
+	}
+
+	seen := make(map[string]bool, len(data.Events))
+	events := make([]keyedEvent, 0, len(data.Events))
+	for _, event := range data.Events {
+		key := event.Key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		at, err := time.Parse(time.RFC3339, event.Date)
+		events = append(events, keyedEvent{event: event, at: at, parsed: err == nil})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].parsed != events[j].parsed {
+			return events[i].parsed
+		}
+		return events[i].at.After(events[j].at)
+	})
+
+	data.Events = make([]models.Event, len(events))
+	for i, e := range events {
+		data.Events[i] = e.event
+	}
+
+	data.FirstEvent, data.LastEvent = nil, nil
+	if len(data.Events) > 0 {
+		last := data.Events[0]
+		first := data.Events[len(data.Events)-1]
+		data.LastEvent, data.FirstEvent = &last, &first
+	}
+}
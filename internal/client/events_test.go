@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// TestOrderEvents - удаление повторов и сортировка событий от новых к старым
+func TestOrderEvents(t *testing.T) {
+	data := &models.TrackData{
+		Events: []models.Event{
+			{Status: "Accepted", Date: "2025-09-16T09:02:31+08:00"},
+			{Status: "Delivered", Date: "2025-09-18T11:53:58+08:00"},
+			{Status: "Departed", Date: "unknown"},
+			{Status: "  delivered ", Date: "2025-09-18T03:53:58Z"},
+			{Status: "In transit", Date: "2025-09-17T20:10:04+08:00"},
+			{Status: "Accepted", Date: "2025-09-16T09:02:31+08:00"},
+		},
+	}
+
+	OrderEvents(data)
+
+	want := []string{"Delivered", "In transit", "Accepted", "Departed"}
+	if len(data.Events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(data.Events), len(want), data.Events)
+	}
+	for i, status := range want {
+		if data.Events[i].Status != status {
+			t.Errorf("event %d = %q, want %q", i, data.Events[i].Status, status)
+		}
+	}
+
+	if data.LastEvent == nil || data.LastEvent.Status != "Delivered" {
+		t.Errorf("LastEvent = %+v, want Delivered", data.LastEvent)
+	}
+	if data.FirstEvent == nil || data.FirstEvent.Status != "Departed" {
+		t.Errorf("FirstEvent = %+v, want Departed", data.FirstEvent)
+	}
+
+	empty := &models.TrackData{}
+	OrderEvents(empty)
+	if empty.FirstEvent != nil || empty.LastEvent != nil {
+		t.Errorf("empty data got first/last events")
+	}
+}
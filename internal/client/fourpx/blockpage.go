@@ -0,0 +1,59 @@
+package fourpx
+
+import (
+	"strings"
+
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+)
+
+// ProviderName names 4PX in metrics, errors and rate limits.
+const ProviderName = "4px"
+
+type blockSignature struct {
+	kind    erors.BlockKind
+	pattern string
+}
+
+// blockSignatures are lowercase fragments of the anti-bot, access-denied and
+// maintenance pages served instead of the tracking page.
+var blockSignatures = []blockSignature{
+	{erors.BlockChallenge, "cf-chl"},
+	{erors.BlockChallenge, "challenge-platform"},
+	{erors.BlockChallenge, "just a moment..."},
+	{erors.BlockChallenge, "captcha"},
+	{erors.BlockChallenge, "geetest"},
+	{erors.BlockChallenge, "nc_1_wrapper"},
+	{erors.BlockChallenge, "slide to verify"},
+	{erors.BlockChallenge, "滑动验证"},
+	{erors.BlockChallenge, "安全验证"},
+	{erors.BlockDenied, "access denied"},
+	{erors.BlockDenied, "403 forbidden"},
+	{erors.BlockDenied, "request blocked"},
+	{erors.BlockDenied, "too many requests"},
+	{erors.BlockDenied, "访问被拒绝"},
+	{erors.BlockDenied, "访问过于频繁"},
+	{erors.BlockMaintenance, "under maintenance"},
+	{erors.BlockMaintenance, "system maintenance"},
+	{erors.BlockMaintenance, "系统维护"},
+	{erors.BlockMaintenance, "维护中"},
+	{erors.BlockMaintenance, "502 bad gateway"},
+	{erors.BlockMaintenance, "503 service temporarily unavailable"},
+}
+
+// detectBlockPage reports whether the content is a block page rather than a
+// tracking result. Pages that contain the result list are never treated as
+// blocked, so a stray "captcha" in a script does not trip the detector.
+func detectBlockPage(content string) *erors.ProviderBlockedError {
+	lower := strings.ToLower(content)
+	if strings.Contains(lower, "next-list-item") {
+		return nil
+	}
+
+	for _, sig := range blockSignatures {
+		if strings.Contains(lower, sig.pattern) {
+			return erors.NewProviderBlockedError(ProviderName, sig.kind, sig.pattern)
+		}
+	}
+
+	return nil
+}
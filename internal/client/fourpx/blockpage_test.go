@@ -0,0 +1,60 @@
+package fourpx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+)
+
+// TestDetectBlockPage - распознавание страниц антибота, запрета доступа и техработ
+func TestDetectBlockPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    erors.BlockKind
+	}{
+		{name: "cloudflare", content: `<title>Just a moment...</title><div id="cf-chl-widget"></div>`, want: erors.BlockChallenge},
+		{name: "slider captcha", content: `<div id="nc_1_wrapper">滑动验证</div>`, want: erors.BlockChallenge},
+		{name: "access denied", content: `<h1>Access Denied</h1>`, want: erors.BlockDenied},
+		{name: "rate limited", content: `<p>访问过于频繁</p>`, want: erors.BlockDenied},
+		{name: "maintenance", content: `<h1>System Maintenance</h1>`, want: erors.BlockMaintenance},
+		{name: "gateway", content: `<center>502 Bad Gateway</center>`, want: erors.BlockMaintenance},
+		{name: "result page mentioning captcha", content: `<script>loadCaptcha()</script><li class="next-list-item">LK517880262CN</li>`},
+		{name: "empty", content: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockedErr := detectBlockPage(tt.content)
+			if tt.want == "" {
+				if blockedErr != nil {
+					t.Errorf("got %v, want no block", blockedErr)
+				}
+				return
+			}
+			if blockedErr == nil || blockedErr.Kind != tt.want || blockedErr.Provider != ProviderName {
+				t.Errorf("got %v, want %s block", blockedErr, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectBlockPageFixtures - настоящие страницы результатов не считаются блокировкой
+func TestDetectBlockPageFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(testdataDir, "*.html"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no HTML fixtures: %v", err)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if blockedErr := detectBlockPage(string(content)); blockedErr != nil {
+			t.Errorf("%s detected as blocked: %v", filepath.Base(file), blockedErr)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package fourpx
+
+// splitCodes groups track codes into chunks of at most maxCodes codes whose
+// comma-joined length, added to prefixLen, stays within maxURLLength. A code
+// that alone exceeds the URL limit still gets a chunk of its own. Zero limits
+// are not enforced.
+func splitCodes(trackCodes []string, maxCodes, prefixLen, maxURLLength int) [][]string {
+	var (
+		chunks  [][]string
+		current []string
+		length  int
+	)
+
+	for _, code := range trackCodes {
+		added := len(code)
+		if len(current) > 0 {
+			added++
+		}
+
+		tooMany := maxCodes > 0 && len(current) >= maxCodes
+		tooLong := maxURLLength > 0 && len(current) > 0 && prefixLen+length+added > maxURLLength
+		if tooMany || tooLong {
+			chunks = append(chunks, current)
+			current, length, added = nil, 0, len(code)
+		}
+
+		current = append(current, code)
+		length += added
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
@@ -0,0 +1,50 @@
+package fourpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSplitCodes - разбиение батча на страницы по количеству кодов и длине URL
+func TestSplitCodes(t *testing.T) {
+	codes := []string{"AAAA1111", "BBBB2222", "CCCC3333", "DDDD4444", "EEEE5555"}
+
+	tests := []struct {
+		name         string
+		maxCodes     int
+		prefixLen    int
+		maxURLLength int
+		want         [][]string
+	}{
+		{
+			name: "no limits",
+			want: [][]string{codes},
+		},
+		{
+			name:     "by code count",
+			maxCodes: 2,
+			want:     [][]string{{"AAAA1111", "BBBB2222"}, {"CCCC3333", "DDDD4444"}, {"EEEE5555"}},
+		},
+		{
+			name:         "by url length",
+			prefixLen:    10,
+			maxURLLength: 10 + 8 + 1 + 8 + 1 + 8,
+			want:         [][]string{{"AAAA1111", "BBBB2222", "CCCC3333"}, {"DDDD4444", "EEEE5555"}},
+		},
+		{
+			name:         "code longer than limit gets its own chunk",
+			prefixLen:    10,
+			maxURLLength: 12,
+			want:         [][]string{{"AAAA1111"}, {"BBBB2222"}, {"CCCC3333"}, {"DDDD4444"}, {"EEEE5555"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitCodes(codes, tt.maxCodes, tt.prefixLen, tt.maxURLLength)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
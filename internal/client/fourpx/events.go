@@ -0,0 +1,63 @@
+package fourpx
+
+import (
+	"strings"
+
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/country"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// eventRules follow the English wording of the 4PX timeline. Negative
+// delivery wording is matched before DELIVERED, since "not delivered" also
+// contains "delivered".
+var eventRules = client.EventRules{
+	Codes: []client.EventRule{
+		{Code: models.EventInfoReceived, Keywords: []string{"information received", "electronic information", "order created", "label created"}},
+		{Code: models.EventCustomsCleared, Keywords: []string{"clearance completed", "released by customs", "customs cleared"}},
+		{Code: models.EventCustoms, Keywords: []string{"customs"}},
+		{Code: models.EventOutForDelivery, Keywords: []string{"out for delivery"}},
+		{Code: models.EventDeliveryFailed, Keywords: []string{"delivery attempt*", "failed delivery", "delivery failed", "unsuccessful delivery", "unable to deliver", "undelivered", "not delivered", "undeliverable"}},
+		{Code: models.EventDelivered, Keywords: []string{"delivered", "signed"}},
+		{Code: models.EventReturned, Keywords: []string{"return*"}},
+		{Code: models.EventException, Keywords: []string{"exception", "held", "lost", "damaged"}},
+		{Code: models.EventDeparted, Keywords: []string{"depart*", "dispatched", "left the"}},
+		{Code: models.EventArrived, Keywords: []string{"arrived", "arrival"}},
+		{Code: models.EventInTransit, Keywords: []string{"transit*", "flight*", "transport*"}},
+		{Code: models.EventAccepted, Keywords: []string{"picked up", "accepted", "received"}},
+	},
+	Placeholders: []string{"SYSTEM"},
+}
+
+// describeEvent fills the structured fields of event from the status text,
+// which 4PX writes as "<prefix> / <description>". The prefix is a
+// "City, Country" location, a facility name or a placeholder.
+func describeEvent(event *models.Event, text string) {
+	prefix, description, found := strings.Cut(text, " / ")
+	if !found {
+		prefix, description = "", text
+	}
+	prefix = strings.TrimSpace(prefix)
+
+	switch {
+	case prefix == "" || eventRules.IsPlaceholder(prefix):
+	case isLocation(prefix):
+		event.Location = prefix
+	default:
+		event.Facility = prefix
+	}
+
+	event.Description = strings.TrimSpace(description)
+	event.Code = eventRules.Classify(event.Description)
+}
+
+// isLocation reports whether text ends with a known country, as in
+// "ALMATY, Kazakhstan".
+func isLocation(text string) bool {
+	i := strings.LastIndex(text, ",")
+	if i < 0 {
+		return false
+	}
+	_, ok := country.Resolve(text[i+1:])
+	return ok
+}
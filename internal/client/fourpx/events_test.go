@@ -0,0 +1,76 @@
+package fourpx
+
+import (
+	"testing"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// TestDescribeEvent - разбор текста события на место, объект, описание и код
+func TestDescribeEvent(t *testing.T) {
+	tests := []struct {
+		text string
+		want models.Event
+	}{
+		{
+			text: "Domestic Air Cargo Termina / Depart from facility to service provider.",
+			want: models.Event{Facility: "Domestic Air Cargo Termina", Description: "Depart from facility to service provider.", Code: models.EventDeparted},
+		},
+		{
+			text: "SYSTEM / Shipment arrived at facility and measured.",
+			want: models.Event{Description: "Shipment arrived at facility and measured.", Code: models.EventArrived},
+		},
+		{
+			text: "ALMATY, Kazakhstan / Customs clearance completed",
+			want: models.Event{Location: "ALMATY, Kazakhstan", Description: "Customs clearance completed", Code: models.EventCustomsCleared},
+		},
+		{
+			text: "Shenzhen Hub, Bay 4 / Handed over to customs",
+			want: models.Event{Facility: "Shenzhen Hub, Bay 4", Description: "Handed over to customs", Code: models.EventCustoms},
+		},
+		{
+			text: "Parcel information received",
+			want: models.Event{Description: "Parcel information received", Code: models.EventInfoReceived},
+		},
+		{
+			text: "Out for delivery",
+			want: models.Event{Description: "Out for delivery", Code: models.EventOutForDelivery},
+		},
+		{
+			text: "Parcel assigned to courier",
+			want: models.Event{Description: "Parcel assigned to courier", Code: models.EventUnknown},
+		},
+		{
+			text: "Item undelivered, addressee absent",
+			want: models.Event{Description: "Item undelivered, addressee absent", Code: models.EventDeliveryFailed},
+		},
+		{
+			text: "Shipment not delivered",
+			want: models.Event{Description: "Shipment not delivered", Code: models.EventDeliveryFailed},
+		},
+		{
+			text: "Delivery failed, will retry",
+			want: models.Event{Description: "Delivery failed, will retry", Code: models.EventDeliveryFailed},
+		},
+		{
+			text: "Delivered, signed by recipient",
+			want: models.Event{Description: "Delivered, signed by recipient", Code: models.EventDelivered},
+		},
+		{
+			text: "Returned to sender",
+			want: models.Event{Description: "Returned to sender", Code: models.EventReturned},
+		},
+		{
+			text: "Something new",
+			want: models.Event{Description: "Something new", Code: models.EventUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		var got models.Event
+		describeEvent(&got, tt.text)
+		if got != tt.want {
+			t.Errorf("describeEvent(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -4,44 +4,67 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/config"
 	"github.com/shamil/proxy_track_service-1/internal/erors"
 	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/proxypool"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
 )
 
 type FourPXClient struct {
-	baseURL     string
-	httpClient  *http.Client
-	hashPattern string
+	baseURL          string
+	hashPattern      string
+	maxCodesPerPage  int
+	maxURLLength     int
+	chunkConcurrency int
+	parseOptions     ParseOptions
+	filter           *resourceFilter
+	proxies          *proxypool.Pool
+	guard            *client.ProviderGuard
+	snapshots        *snapshotRecorder
+	drift            *client.DriftDetector
+	lifetime         *client.Lifetime
 }
 
-func NewFourPXClient(baseURL string, hashpattern string, timeout time.Duration) client.ExternalAPIClient {
-	return &FourPXClient{
-		baseURL:     strings.TrimSuffix(baseURL, "/"),
-		hashPattern: hashpattern,
-		httpClient: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				IdleConnTimeout:     30 * time.Second,
-				DisableCompression:  false,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 10,
-			},
-		},
+func NewFourPXClient(
+	cfg config.ExternalConfig,
+	proxies *proxypool.Pool,
+	snapshots repository.SnapshotRepository,
+	captureScreenshots bool,
+) client.ExternalAPIClient {
+	c := &FourPXClient{
+		baseURL:          strings.TrimSuffix(cfg.BaseURL, "/"),
+		hashPattern:      cfg.HashPattern,
+		maxCodesPerPage:  cfg.MaxCodesPerPage,
+		maxURLLength:     cfg.MaxURLLength,
+		chunkConcurrency: cfg.ChunkConcurrency,
+		parseOptions:     NewParseOptions(cfg),
+		filter:           newResourceFilter(cfg.BlockResourceTypes, cfg.BlockURLPatterns, cfg.AllowURLPatterns),
+		proxies:          proxies,
+		guard:            client.NewProviderGuard(ProviderName, cfg.BackoffBase, cfg.BackoffMax),
+		drift:            client.NewDriftDetector(ProviderName, cfg.DriftWindow, cfg.DriftThreshold, cfg.DriftMinSamples),
+		lifetime:         client.NewLifetime(),
 	}
+	if snapshots != nil {
+		c.snapshots = &snapshotRecorder{store: snapshots, screenshots: captureScreenshots}
+	}
+
+	return c
 }
 
 func (c *FourPXClient) TrackPackage(ctx context.Context, trackCode string) (*models.TrackData, error) {
-	if !c.isValidTrackCode(trackCode) {
+	if !client.IsValidTrackCode(trackCode) {
 		log.Printf("client.TrackPackage.InvalidCode: %s", trackCode)
 		return nil, erors.NewClientError("invalid tracking code format", erors.ErrInvalidTrackCode)
 	}
@@ -64,35 +87,105 @@ func (c *FourPXClient) TrackPackagesBatch(ctx context.Context, trackCodes []stri
 		return nil, erors.NewInternalError("BATCH_EMPTY", "no track codes provided", nil)
 	}
 
-	htmlContent, err := c.scrapeWithChromedp(ctx, trackCodes)
+	if err := c.guard.Check(); err != nil {
+		return nil, erors.NewClientError("tracking provider blocked the request", err)
+	}
+
+	chunks := splitCodes(trackCodes, c.maxCodesPerPage, len(c.baseURL)+len(c.hashPattern), c.maxURLLength)
+	if len(chunks) > 1 {
+		log.Printf("client.TrackPackagesBatch.Chunked: %d codes split into %d pages", len(trackCodes), len(chunks))
+	}
+
+	ctx, cancel := c.lifetime.Bind(ctx)
+	defer cancel()
+
+	return client.RunChunks(ctx, chunks, c.chunkConcurrency, c.trackPage)
+}
+
+// Close kills the browsers of in-flight scrapes.
+func (c *FourPXClient) Close() error {
+	c.lifetime.Close()
+	return nil
+}
+
+// trackPage scrapes and parses a single result page for the given codes.
+func (c *FourPXClient) trackPage(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error) {
+	if err := c.guard.Check(); err != nil {
+		return nil, erors.NewClientError("tracking provider blocked the request", err)
+	}
+
+	batchID, chunk := client.BatchID(ctx)
+
+	var proxy *proxypool.Proxy
+	if c.proxies != nil {
+		proxy = c.proxies.Next()
+	}
+
+	start := time.Now()
+	page, err := c.scrapeWithChromedp(ctx, trackCodes, proxy)
+
+	var blockedErr *erors.ProviderBlockedError
+	if err == nil {
+		blockedErr = detectBlockPage(page.html)
+	}
+
+	if c.proxies != nil {
+		if blockedErr != nil {
+			c.proxies.Report(proxy, time.Since(start), blockedErr)
+		} else {
+			c.proxies.Report(proxy, time.Since(start), err)
+		}
+	}
+
 	if err != nil {
-		log.Printf("client.TrackPackagesBatch.ScrapingError: %v", err)
+		log.Printf("client.TrackPackagesBatch.ScrapingError: batch %s chunk %d: %v", batchID, chunk, err)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonScrapeError, page)
 		return nil, erors.NewClientError("tracking service temporarily unavailable", erors.ErrServiceUnavailable)
 	}
 
-	results, err := ParseHTML(htmlContent, trackCodes)
+	if blockedErr != nil {
+		c.guard.RecordBlock(blockedErr)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonBlocked, page)
+		return nil, erors.NewClientError("tracking provider blocked the request", blockedErr)
+	}
+	c.guard.RecordSuccess()
+
+	results, signals, err := ParseHTML(page.html, trackCodes, c.parseOptions)
 	if err != nil {
-		log.Printf("client.TrackPackagesBatch.ParsingError: %v", err)
+		log.Printf("client.TrackPackagesBatch.ParsingError: batch %s chunk %d: %v", batchID, chunk, err)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonParseError, page)
 		return nil, erors.NewClientError("tracking service temporarily unavailable", erors.ErrServiceUnavailable)
 	}
 
+	c.drift.Observe(signals)
+	if signals.Empty() {
+		log.Printf("client.TrackPackagesBatch.EmptyResult: batch %s chunk %d: %+v", batchID, chunk, signals)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonEmptyResult, page)
+	}
+
 	return results, nil
 }
 
-func (c *FourPXClient) scrapeWithChromedp(ctx context.Context, trackCodes []string) (string, error) {
+// scrapeWithChromedp loads the tracking page. The returned page is never nil;
+// on failure it holds whatever could still be captured for snapshots.
+func (c *FourPXClient) scrapeWithChromedp(ctx context.Context, trackCodes []string, proxy *proxypool.Proxy) (*scrapedPage, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	trackCodeParam := strings.Join(trackCodes, ",")
+	url := fmt.Sprintf("%s%s%s", c.baseURL, c.hashPattern, trackCodeParam)
+	page := &scrapedPage{url: url}
+
 	browserPath, err := findBrowserPath()
 	if err != nil {
 		log.Printf("client.scrapeWithChromedp.BrowserNotFound: %v", err)
-		return "", erors.NewInternalError("BROWSER_NOT_FOUND", "browser not found", err)
+		return page, erors.NewInternalError("BROWSER_NOT_FOUND", "browser not found", err)
 	}
 
 	allocatorOpts := append(
 		chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.ExecPath(browserPath),
-		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		chromedp.UserAgent(browserUserAgent),
 		chromedp.WindowSize(1920, 1080),
 		chromedp.Flag("headless", true),
 		chromedp.Flag("no-sandbox", true),
@@ -100,6 +193,9 @@ func (c *FourPXClient) scrapeWithChromedp(ctx context.Context, trackCodes []stri
 		chromedp.Flag("disable-web-security", true),
 		chromedp.Flag("ignore-certificate-errors", true),
 	)
+	if proxy != nil {
+		allocatorOpts = append(allocatorOpts, chromedp.ProxyServer(proxy.Server()))
+	}
 
 	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocatorOpts...)
 	defer cancelAlloc()
@@ -107,12 +203,8 @@ func (c *FourPXClient) scrapeWithChromedp(ctx context.Context, trackCodes []stri
 	tabCtx, cancelTab := chromedp.NewContext(allocCtx)
 	defer cancelTab()
 
-	trackCodeParam := strings.Join(trackCodes, ",")
-	url := fmt.Sprintf("%s%s%s", c.baseURL, c.hashPattern, trackCodeParam)
-
-	var htmlContent string
-
 	err = chromedp.Run(tabCtx,
+		c.interceptRequests(tabCtx, proxy),
 		chromedp.Navigate(url),
 		chromedp.ActionFunc(func(ctx context.Context) error {
 
@@ -141,21 +233,90 @@ func (c *FourPXClient) scrapeWithChromedp(ctx context.Context, trackCodes []stri
 			return nil
 		}),
 
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			node, err := dom.GetDocument().Do(ctx)
-			if err != nil {
-				return err
-			}
-			htmlContent, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
-			return err
-		}),
+		c.capturePage(page),
 	)
 
 	if err != nil {
-		return "", fmt.Errorf("chromedp run failed: %w", err)
+		if c.snapshots != nil && tabCtx.Err() == nil {
+			if captureErr := chromedp.Run(tabCtx, c.capturePage(page)); captureErr != nil {
+				log.Printf("client.scrapeWithChromedp.CaptureError: %v", captureErr)
+			}
+		}
+		return page, fmt.Errorf("chromedp run failed: %w", err)
 	}
 
-	return htmlContent, nil
+	return page, nil
+}
+
+// capturePage stores the document HTML into page, plus a screenshot when
+// snapshots with screenshots are enabled.
+func (c *FourPXClient) capturePage(page *scrapedPage) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		node, err := dom.GetDocument().Do(ctx)
+		if err != nil {
+			return err
+		}
+		page.html, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if c.snapshots != nil && c.snapshots.screenshots {
+			if err := chromedp.CaptureScreenshot(&page.screenshot).Do(ctx); err != nil {
+				log.Printf("client.capturePage.ScreenshotError: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// interceptRequests pauses every request of the tab, drops the ones the
+// resource filter rejects before they hit the network and answers proxy
+// authentication challenges with the proxy credentials.
+func (c *FourPXClient) interceptRequests(tabCtx context.Context, proxy *proxypool.Proxy) chromedp.Action {
+	handleAuth := proxy != nil && proxy.HasCredentials()
+	if !c.filter.enabled() && !handleAuth {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				execCtx := cdp.WithExecutor(tabCtx, chromedp.FromContext(tabCtx).Target)
+
+				var err error
+				if c.filter.shouldBlock(e.ResourceType, e.Request.URL) {
+					c.filter.recordBlocked(e.ResourceType)
+					err = fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx)
+				} else {
+					err = fetch.ContinueRequest(e.RequestID).Do(execCtx)
+				}
+				if err != nil && tabCtx.Err() == nil {
+					log.Printf("client.interceptRequests.Error: %v", err)
+				}
+			}()
+
+		case *fetch.EventAuthRequired:
+			go func() {
+				execCtx := cdp.WithExecutor(tabCtx, chromedp.FromContext(tabCtx).Target)
+
+				resp := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+				if handleAuth && e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+					resp = &fetch.AuthChallengeResponse{
+						Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+						Username: proxy.Username,
+						Password: proxy.Password,
+					}
+				}
+				if err := fetch.ContinueWithAuth(e.RequestID, resp).Do(execCtx); err != nil && tabCtx.Err() == nil {
+					log.Printf("client.interceptRequests.AuthError: %v", err)
+				}
+			}()
+		}
+	})
+
+	return fetch.Enable().WithHandleAuthRequests(handleAuth)
 }
 
 func findBrowserPath() (string, error) {
@@ -189,24 +350,11 @@ func findBrowserPath() (string, error) {
 	return "", fmt.Errorf("no browser found. Please install Chrome or Chromium")
 }
 
-func (c *FourPXClient) isValidTrackCode(trackCode string) bool {
-	if len(trackCode) < 8 || len(trackCode) > 20 {
-		return false
-	}
-
-	hasLetter := false
-	hasDigit := false
-
-	for _, char := range trackCode {
-		if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
-			hasLetter = true
-		}
-		if char >= '0' && char <= '9' {
-			hasDigit = true
-		}
-	}
-
-	return hasLetter && hasDigit
+func (c *FourPXClient) ProviderStats() client.ProviderStats {
+	stats := c.guard.Stats()
+	drift := c.drift.Status()
+	stats.Drift = &drift
+	return stats
 }
 
 func (c *FourPXClient) Health(ctx context.Context) error {
@@ -0,0 +1,434 @@
+package fourpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/proxypool"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// FourPXHTTPClient talks to the JSON backend used by the track.4px.com frontend
+// directly, without launching a browser. The frontend sends the query as
+// plain JSON with no signature or token; only its browser headers are
+// reproduced. Proxies, snapshots and drift detection work as in the browser
+// client, except that snapshots hold the raw response instead of HTML.
+type FourPXHTTPClient struct {
+	baseURL          string
+	apiPath          string
+	language         string
+	retryCount       int
+	maxCodesPerPage  int
+	chunkConcurrency int
+	parseOptions     ParseOptions
+	httpClient       *http.Client
+	proxies          *proxypool.Pool
+	guard            *client.ProviderGuard
+	snapshots        *snapshotRecorder
+	drift            *client.DriftDetector
+	lifetime         *client.Lifetime
+}
+
+type proxyKey struct{}
+
+func NewFourPXHTTPClient(cfg config.ExternalConfig, proxies *proxypool.Pool, snapshots repository.SnapshotRepository) client.ExternalAPIClient {
+	c := &FourPXHTTPClient{
+		baseURL:          strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiPath:          cfg.APIPath,
+		language:         cfg.Language,
+		retryCount:       cfg.RetryCount,
+		maxCodesPerPage:  cfg.MaxCodesPerPage,
+		chunkConcurrency: cfg.ChunkConcurrency,
+		parseOptions:     NewParseOptions(cfg),
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				Proxy:               proxyFromContext,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     30 * time.Second,
+				DisableCompression:  false,
+				DisableKeepAlives:   false,
+				MaxIdleConnsPerHost: 10,
+			},
+		},
+		proxies:  proxies,
+		guard:    client.NewProviderGuard(ProviderName, cfg.BackoffBase, cfg.BackoffMax),
+		drift:    client.NewDriftDetector(ProviderName, cfg.DriftWindow, cfg.DriftThreshold, cfg.DriftMinSamples),
+		lifetime: client.NewLifetime(),
+	}
+	if snapshots != nil {
+		c.snapshots = &snapshotRecorder{store: snapshots}
+	}
+
+	return c
+}
+
+// proxyFromContext routes a request through the pool proxy its chunk was
+// given, credentials included. Requests without one go out directly.
+func proxyFromContext(req *http.Request) (*url.URL, error) {
+	proxy, ok := req.Context().Value(proxyKey{}).(*proxypool.Proxy)
+	if !ok || proxy == nil {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy.Server())
+	if err != nil {
+		return nil, err
+	}
+	if proxy.HasCredentials() {
+		proxyURL.User = url.UserPassword(proxy.Username, proxy.Password)
+	}
+	return proxyURL, nil
+}
+
+type trackQuery struct {
+	QueryCodes        []string `json:"queryCodes"`
+	Language          string   `json:"language"`
+	TranslateLanguage string   `json:"translateLanguage"`
+}
+
+type trackAPIResponse struct {
+	Result  int             `json:"result"`
+	Message string          `json:"message"`
+	Data    []trackAPIEntry `json:"data"`
+}
+
+type trackAPIEntry struct {
+	QueryCode   string          `json:"queryCode"`
+	CtStartName string          `json:"ctStartName"`
+	CtEndName   string          `json:"ctEndName"`
+	Tracks      []trackAPIEvent `json:"tracks"`
+}
+
+type trackAPIEvent struct {
+	TkCode     string `json:"tkCode"`
+	TkDesc     string `json:"tkDesc"`
+	TkLocation string `json:"tkLocation"`
+	TkTimezone string `json:"tkTimezone"`
+	TkDate     string `json:"tkDate"`
+}
+
+func (c *FourPXHTTPClient) TrackPackage(ctx context.Context, trackCode string) (*models.TrackData, error) {
+	if !client.IsValidTrackCode(trackCode) {
+		log.Printf("client.TrackPackage.InvalidCode: %s", trackCode)
+		return nil, erors.NewClientError("invalid tracking code format", erors.ErrInvalidTrackCode)
+	}
+
+	results, err := c.TrackPackagesBatch(ctx, []string{trackCode})
+	if err != nil {
+		return nil, err
+	}
+
+	if data, exists := results[trackCode]; exists {
+		return data, nil
+	}
+
+	log.Printf("client.TrackPackage.NotFound: %s", trackCode)
+	return nil, erors.NewClientError("tracking code not found", erors.ErrTrackCodeNotFound)
+}
+
+func (c *FourPXHTTPClient) TrackPackagesBatch(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error) {
+	if len(trackCodes) == 0 {
+		return nil, erors.NewInternalError("BATCH_EMPTY", "no track codes provided", nil)
+	}
+
+	ctx, cancel := c.lifetime.Bind(ctx)
+	defer cancel()
+
+	chunks := splitCodes(trackCodes, c.maxCodesPerPage, 0, 0)
+	return client.RunChunks(ctx, chunks, c.chunkConcurrency, c.trackChunk)
+}
+
+// Close aborts in-flight requests and drops idle connections.
+func (c *FourPXHTTPClient) Close() error {
+	c.lifetime.Close()
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (c *FourPXHTTPClient) trackChunk(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error) {
+	if err := c.guard.Check(); err != nil {
+		return nil, erors.NewClientError("tracking provider blocked the request", err)
+	}
+
+	batchID, chunk := client.BatchID(ctx)
+
+	var proxy *proxypool.Proxy
+	if c.proxies != nil {
+		proxy = c.proxies.Next()
+		ctx = context.WithValue(ctx, proxyKey{}, proxy)
+	}
+
+	var (
+		resp *trackAPIResponse
+		page *scrapedPage
+		err  error
+	)
+	for attempt := 0; attempt <= c.retryCount; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, erors.NewClientError("request timeout", erors.ErrRequestTimeout)
+			}
+		}
+
+		start := time.Now()
+		resp, page, err = c.queryTracks(ctx, trackCodes)
+		if c.proxies != nil {
+			c.proxies.Report(proxy, time.Since(start), proxyFailure(err))
+		}
+		if err == nil || !isRetryable(err) {
+			break
+		}
+		log.Printf("client.TrackPackagesBatch.Retry: batch %s chunk %d: attempt %d: %v", batchID, chunk, attempt+1, err)
+	}
+
+	if blockedErr, ok := erors.AsProviderBlocked(err); ok {
+		c.guard.RecordBlock(blockedErr)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonBlocked, page)
+		return nil, erors.NewClientError("tracking provider blocked the request", blockedErr)
+	}
+
+	if err != nil {
+		log.Printf("client.TrackPackagesBatch.RequestError: batch %s chunk %d: %v", batchID, chunk, err)
+		reason := snapshotReasonScrapeError
+		if erors.GetErrorCode(err) == "RESPONSE_DECODE" || erors.GetErrorCode(err) == "API_RESULT" {
+			reason = snapshotReasonParseError
+		}
+		c.snapshots.record(batchID, trackCodes, reason, page)
+		return nil, erors.NewClientError("tracking service temporarily unavailable", erors.ErrServiceUnavailable)
+	}
+	c.guard.RecordSuccess()
+
+	results := convertAPIResponse(resp, trackCodes, c.parseOptions)
+
+	signals := apiSignals(resp, trackCodes, results)
+	c.drift.Observe(signals)
+	if signals.Empty() {
+		log.Printf("client.TrackPackagesBatch.EmptyResult: batch %s chunk %d: %+v", batchID, chunk, signals)
+		c.snapshots.record(batchID, trackCodes, snapshotReasonEmptyResult, page)
+	}
+
+	return results, nil
+}
+
+// proxyFailure returns the part of a query error that is the proxy's fault:
+// network errors and block answers. Other errors come from the provider and
+// do not count against the proxy.
+func proxyFailure(err error) error {
+	if err == nil {
+		return nil
+	}
+	if erors.IsProviderBlocked(err) {
+		return err
+	}
+	switch erors.GetErrorCode(err) {
+	case "HTTP_REQUEST", "HTTP_READ":
+		return err
+	}
+	return nil
+}
+
+// queryTracks posts one query. The returned page holds the raw response, when
+// there is one, for snapshots.
+func (c *FourPXHTTPClient) queryTracks(ctx context.Context, trackCodes []string) (*trackAPIResponse, *scrapedPage, error) {
+	endpoint := c.baseURL + c.apiPath
+	page := &scrapedPage{url: endpoint}
+
+	body, err := json.Marshal(trackQuery{
+		QueryCodes:        trackCodes,
+		Language:          c.language,
+		TranslateLanguage: c.language,
+	})
+	if err != nil {
+		return nil, page, erors.NewInternalError("REQUEST_ENCODE", "failed to encode request", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, page, erors.NewInternalError("REQUEST_BUILD", "failed to create request", err)
+	}
+	c.setBrowserHeaders(req)
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, page, erors.NewInternalError("HTTP_REQUEST", "HTTP request failed", fmt.Errorf("%w: %v", erors.ErrInternalNetwork, err))
+	}
+	defer httpResp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(httpResp.Body, 10<<20))
+	page.html = string(payload)
+	if err != nil {
+		return nil, page, erors.NewInternalError("HTTP_READ", "failed to read response", fmt.Errorf("%w: %v", erors.ErrInternalNetwork, err))
+	}
+
+	if blockedErr := detectBlockResponse(httpResp.StatusCode, payload); blockedErr != nil {
+		return nil, page, blockedErr
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, page, &statusError{code: httpResp.StatusCode}
+	}
+
+	var resp trackAPIResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return nil, page, erors.NewInternalError("RESPONSE_DECODE", "failed to decode response", fmt.Errorf("%w: %v", erors.ErrInternalParsing, err))
+	}
+
+	if resp.Result != 1 {
+		return nil, page, erors.NewInternalError("API_RESULT", "unexpected API result: "+resp.Message, erors.ErrInternalScraping)
+	}
+
+	return &resp, page, nil
+}
+
+// setBrowserHeaders reproduces the headers the 4PX web frontend sends; the
+// backend rejects requests that do not look like they come from the site.
+func (c *FourPXHTTPClient) setBrowserHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("User-Agent", browserUserAgent)
+	req.Header.Set("Origin", c.baseURL)
+	req.Header.Set("Referer", c.baseURL+"/")
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+}
+
+// detectBlockResponse recognises block pages served in place of JSON, as well
+// as the bare 403/429 answers the backend gives to throttled clients.
+func detectBlockResponse(statusCode int, payload []byte) *erors.ProviderBlockedError {
+	if json.Valid(payload) && statusCode == http.StatusOK {
+		return nil
+	}
+
+	if blockedErr := detectBlockPage(string(payload)); blockedErr != nil {
+		return blockedErr
+	}
+
+	switch statusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		return erors.NewProviderBlockedError(ProviderName, erors.BlockDenied, fmt.Sprintf("HTTP %d", statusCode))
+	}
+
+	return nil
+}
+
+func (c *FourPXHTTPClient) ProviderStats() client.ProviderStats {
+	stats := c.guard.Stats()
+	drift := c.drift.Status()
+	stats.Drift = &drift
+	return stats
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d", e.code)
+}
+
+func isRetryable(err error) bool {
+	if se, ok := err.(*statusError); ok {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return erors.GetErrorCode(err) == "HTTP_REQUEST" || erors.GetErrorCode(err) == "HTTP_READ"
+}
+
+// convertAPIResponse maps API entries to the requested codes. Codes the API
+// did not return are left out, so they are reported as not found.
+func convertAPIResponse(resp *trackAPIResponse, trackCodes []string, opts ParseOptions) map[string]*models.TrackData {
+	entries := make(map[string]trackAPIEntry, len(resp.Data))
+	for _, entry := range resp.Data {
+		entries[strings.ToUpper(entry.QueryCode)] = entry
+	}
+
+	results := make(map[string]*models.TrackData, len(trackCodes))
+	for _, trackCode := range trackCodes {
+		entry, exists := entries[strings.ToUpper(trackCode)]
+		if !exists {
+			continue
+		}
+
+		origin, destination := resolveCountries(entry.CtStartName, entry.CtEndName)
+		data := &models.TrackData{
+			Countries:   countryCodes(origin, destination),
+			Origin:      origin,
+			Destination: destination,
+			Events:      apiEvents(entry.Tracks, opts),
+		}
+		client.OrderEvents(data)
+		results[trackCode] = data
+	}
+
+	return results
+}
+
+// apiSignals maps an API response onto the layout signals of a result page, so
+// drift detection treats both clients alike: entries stand for list items and
+// tracks for timeline items.
+func apiSignals(resp *trackAPIResponse, trackCodes []string, results map[string]*models.TrackData) client.LayoutSignals {
+	signals := client.LayoutSignals{
+		CodesRequested: len(trackCodes),
+		ListItems:      len(resp.Data),
+		MatchedCodes:   len(results),
+	}
+	for _, entry := range resp.Data {
+		for _, track := range entry.Tracks {
+			signals.TimelineItems++
+			if extractDateTime(track.TkDate) != "" {
+				signals.DateMatches++
+			}
+		}
+	}
+	return signals
+}
+
+func apiEvents(tracks []trackAPIEvent, opts ParseOptions) []models.Event {
+	events := make([]models.Event, 0, len(tracks))
+	for _, track := range tracks {
+		dateTime := extractDateTime(track.TkDate)
+		if dateTime == "" {
+			continue
+		}
+
+		description := cleanStatusText(track.TkDesc)
+		if description == "" {
+			continue
+		}
+		status := description
+		location := strings.TrimSpace(track.TkLocation)
+		if location != "" {
+			status = location + " / " + description
+		}
+
+		loc := opts.Location
+		if offsetLoc := extractOffset(track.TkTimezone); offsetLoc != nil {
+			loc = offsetLoc
+		}
+
+		event := newEvent(status, dateTime, loc, opts.DualTimes)
+		describeEvent(event, description)
+		if location != "" {
+			event.Location = location
+		}
+		events = append(events, *event)
+	}
+
+	return events
+}
@@ -0,0 +1,242 @@
+package fourpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+var utc8 = time.FixedZone("UTC+08:00", 8*60*60)
+
+func newTestHTTPClient(t *testing.T, handler http.HandlerFunc) *FourPXHTTPClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := NewFourPXHTTPClient(config.ExternalConfig{
+		BaseURL:         server.URL,
+		APIPath:         "/track/query",
+		Timeout:         5 * time.Second,
+		RetryCount:      2,
+		DefaultTimezone: "Asia/Shanghai",
+		BackoffBase:     time.Minute,
+		BackoffMax:      time.Hour,
+	}, nil, nil).(*FourPXHTTPClient)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func writeAPIResponse(w http.ResponseWriter, entries ...trackAPIEntry) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trackAPIResponse{Result: 1, Data: entries})
+}
+
+// TestConvertAPIResponse - коды, которых нет в ответе API, не попадают в результат
+func TestConvertAPIResponse(t *testing.T) {
+	resp := &trackAPIResponse{Result: 1, Data: []trackAPIEntry{{
+		QueryCode:   "lk517880262cn",
+		CtStartName: "China",
+		CtEndName:   "Kazakhstan",
+		Tracks: []trackAPIEvent{
+			{TkDesc: "Delivered", TkLocation: "ALMATY", TkDate: "2025-10-02 06:41:12"},
+			{TkDesc: "Shipment arrived at facility", TkDate: "2025-09-17 20:10:04"},
+		},
+	}}}
+
+	results := convertAPIResponse(resp, []string{"LK517880262CN", "RR000000000RU"}, ParseOptions{Location: utc8})
+
+	if _, exists := results["RR000000000RU"]; exists {
+		t.Errorf("missing code got data: %+v", results["RR000000000RU"])
+	}
+	data, exists := results["LK517880262CN"]
+	if !exists {
+		t.Fatalf("results = %v, want LK517880262CN matched case-insensitively", results)
+	}
+	if len(data.Countries) != 2 || data.Countries[0] != "CN" || data.Countries[1] != "KZ" {
+		t.Errorf("Countries = %v, want [CN KZ]", data.Countries)
+	}
+	if len(data.Events) != 2 || data.LastEvent == nil || data.LastEvent.Code != models.EventDelivered {
+		t.Errorf("events = %+v, last = %+v", data.Events, data.LastEvent)
+	}
+}
+
+// TestAPIEvents - разбор событий API: пропуск неполных, локация и часовой пояс
+func TestAPIEvents(t *testing.T) {
+	tracks := []trackAPIEvent{
+		{TkDesc: "Departed from facility", TkLocation: " SHENZHEN ", TkTimezone: "UTC+08:00", TkDate: "2025-09-18 11:53:58"},
+		{TkDesc: "Customs clearance completed", TkTimezone: "UTC+06:00", TkDate: "2025-09-28 14:20:00"},
+		{TkDesc: "No date", TkDate: "pending"},
+		{TkDesc: "   ", TkDate: "2025-09-19 10:00:00"},
+	}
+
+	events := apiEvents(tracks, ParseOptions{Location: time.UTC})
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+
+	if events[0].Status != "SHENZHEN / Departed from facility" || events[0].Location != "SHENZHEN" {
+		t.Errorf("first event = %+v", events[0])
+	}
+	if events[0].Date != "2025-09-18T11:53:58+08:00" {
+		t.Errorf("first event date = %s", events[0].Date)
+	}
+	if events[1].Status != "Customs clearance completed" || events[1].Location != "" {
+		t.Errorf("second event = %+v", events[1])
+	}
+	if events[1].Date != "2025-09-28T14:20:00+06:00" {
+		t.Errorf("second event date = %s", events[1].Date)
+	}
+}
+
+// TestIsRetryable - повторяются только 5xx, 429 и сетевые ошибки
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &statusError{code: http.StatusBadGateway}, true},
+		{"too many requests", &statusError{code: http.StatusTooManyRequests}, true},
+		{"bad request", &statusError{code: http.StatusBadRequest}, false},
+		{"network", erors.NewInternalError("HTTP_REQUEST", "HTTP request failed", erors.ErrInternalNetwork), true},
+		{"read", erors.NewInternalError("HTTP_READ", "failed to read response", erors.ErrInternalNetwork), true},
+		{"decode", erors.NewInternalError("RESPONSE_DECODE", "failed to decode response", erors.ErrInternalParsing), false},
+		{"plain", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectBlockResponse - страницы блокировки и голые 403/429 вместо JSON
+func TestDetectBlockResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		payload string
+		want    erors.BlockKind
+	}{
+		{name: "json ok", status: http.StatusOK, payload: `{"result":1,"data":[]}`},
+		{name: "challenge page", status: http.StatusOK, payload: `<html><title>Just a moment...</title></html>`, want: erors.BlockChallenge},
+		{name: "maintenance page", status: http.StatusServiceUnavailable, payload: `<h1>System maintenance</h1>`, want: erors.BlockMaintenance},
+		{name: "bare forbidden", status: http.StatusForbidden, payload: ``, want: erors.BlockDenied},
+		{name: "json throttled", status: http.StatusTooManyRequests, payload: `{"message":"slow down"}`, want: erors.BlockDenied},
+		{name: "plain server error", status: http.StatusInternalServerError, payload: `oops`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockedErr := detectBlockResponse(tt.status, []byte(tt.payload))
+			if tt.want == "" {
+				if blockedErr != nil {
+					t.Errorf("got %v, want no block", blockedErr)
+				}
+				return
+			}
+			if blockedErr == nil || blockedErr.Kind != tt.want || blockedErr.Provider != ProviderName {
+				t.Errorf("got %v, want %s block", blockedErr, tt.want)
+			}
+		})
+	}
+}
+
+// TestTrackChunkRetries - повтор после 5xx и отсутствие повтора после 4xx
+func TestTrackChunkRetries(t *testing.T) {
+	t.Run("recovers after server error", func(t *testing.T) {
+		var requests atomic.Int32
+		c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			writeAPIResponse(w, trackAPIEntry{QueryCode: "LK517880262CN", CtStartName: "China", CtEndName: "Kazakhstan"})
+		})
+
+		results, err := c.trackChunk(context.Background(), []string{"LK517880262CN"})
+		if err != nil {
+			t.Fatalf("trackChunk failed: %v", err)
+		}
+		if _, exists := results["LK517880262CN"]; !exists || requests.Load() != 2 {
+			t.Errorf("results = %v after %d requests, want data after 2", results, requests.Load())
+		}
+	})
+
+	t.Run("gives up on client error", func(t *testing.T) {
+		var requests atomic.Int32
+		c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		_, err := c.trackChunk(context.Background(), []string{"LK517880262CN"})
+		if !errors.Is(err, erors.ErrServiceUnavailable) || requests.Load() != 1 {
+			t.Errorf("err = %v after %d requests, want unavailable after 1", err, requests.Load())
+		}
+	})
+
+	t.Run("records block", func(t *testing.T) {
+		var requests atomic.Int32
+		c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		_, err := c.trackChunk(context.Background(), []string{"LK517880262CN"})
+		if !erors.IsProviderBlocked(err) || requests.Load() != 1 {
+			t.Fatalf("err = %v after %d requests, want blocked after 1", err, requests.Load())
+		}
+		if _, err := c.trackChunk(context.Background(), []string{"LK517880262CN"}); !erors.IsProviderBlocked(err) || requests.Load() != 1 {
+			t.Errorf("second call err = %v, want the guard to refuse without a request", err)
+		}
+	})
+}
+
+// TestTrackChunkCancelled - отмена контекста прерывает ожидание перед повтором
+func TestTrackChunkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	start := time.Now()
+	_, err := c.trackChunk(ctx, []string{"LK517880262CN"})
+	if !errors.Is(err, erors.ErrRequestTimeout) {
+		t.Errorf("err = %v, want request timeout", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("made %d requests after cancellation, want 1", requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("returned after %s, want without waiting out the retry delay", elapsed)
+	}
+}
+
+// TestTrackPackageNotFound - код, отсутствующий в ответе API, отдается как не найденный
+func TestTrackPackageNotFound(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIResponse(w)
+	})
+
+	if _, err := c.TrackPackage(context.Background(), "LK517880262CN"); !errors.Is(err, erors.ErrTrackCodeNotFound) {
+		t.Errorf("err = %v, want not found", err)
+	}
+}
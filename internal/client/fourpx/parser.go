@@ -2,71 +2,137 @@ package fourpx
 
 import (
 	"fmt"
+	"log"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/country"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 )
 
-func ParseHTML(htmlContent string, trackCodes []string) (map[string]*models.TrackData, error) {
+var (
+	dateTimeRe   = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+	utcOffsetRe  = regexp.MustCompile(`UTC\s*([+-])(\d{1,2})(?::?(\d{2}))?`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// ParseOptions controls how event times are interpreted.
+type ParseOptions struct {
+	// Location is used for events whose UTC offset is not shown on the page.
+	Location *time.Location
+	// DualTimes adds the UTC time and the original local wall-clock time to
+	// every event.
+	DualTimes bool
+}
+
+// NewParseOptions builds parse options from the client config. An unknown
+// timezone name falls back to UTC+08:00, the zone 4PX reports in.
+func NewParseOptions(cfg config.ExternalConfig) ParseOptions {
+	loc, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		log.Printf("client.NewParseOptions.InvalidTimezone: %q: %v", cfg.DefaultTimezone, err)
+		loc = time.FixedZone("UTC+08:00", 8*60*60)
+	}
+
+	return ParseOptions{
+		Location:  loc,
+		DualTimes: cfg.DualTimes,
+	}
+}
+
+// ParseHTML extracts tracking data for every requested code from a 4PX result
+// page, together with layout signals used for drift detection.
+func ParseHTML(htmlContent string, trackCodes []string, opts ParseOptions) (map[string]*models.TrackData, client.LayoutSignals, error) {
 	results := make(map[string]*models.TrackData)
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, client.LayoutSignals{}, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
+	signals := collectSignals(doc, trackCodes)
+
 	for _, trackCode := range trackCodes {
-		trackData := parseTrackData(doc, trackCode)
-		if trackData != nil {
+		// Codes the page does not list are left out so callers report them as
+		// not found instead of caching an empty placeholder.
+		if trackData := parseTrackData(doc, trackCode, opts); trackData != nil {
+			signals.MatchedCodes++
 			results[trackCode] = trackData
-		} else {
-			results[trackCode] = &models.TrackData{
-				Countries: []string{"Unknown", "Unknown"},
-				Events:    []models.Event{},
-			}
 		}
 	}
 
-	return results, nil
+	return results, signals, nil
 }
 
-func parseTrackData(doc *goquery.Document, trackCode string) *models.TrackData {
+func collectSignals(doc *goquery.Document, trackCodes []string) client.LayoutSignals {
+	signals := client.LayoutSignals{
+		CodesRequested: len(trackCodes),
+		ListItems:      doc.Find(".next-list-item").Length(),
+	}
+
+	doc.Find(".next-timeline-item").Each(func(i int, s *goquery.Selection) {
+		signals.TimelineItems++
+		if dateTimeRe.MatchString(s.Find(".next-timeline-item-left-content").Text()) {
+			signals.DateMatches++
+		}
+	})
+
+	return signals
+}
+
+func parseTrackData(doc *goquery.Document, trackCode string, opts ParseOptions) *models.TrackData {
 	listItem := doc.Find(fmt.Sprintf(".next-list-item:contains('%s')", trackCode)).First()
 	if listItem.Length() == 0 {
 		return nil
 	}
 
-	return &models.TrackData{
-		Countries: extractCountries(listItem),
-		Events:    extractEvents(doc),
+	origin, destination := extractCountries(listItem)
+	data := &models.TrackData{
+		Countries:   countryCodes(origin, destination),
+		Origin:      origin,
+		Destination: destination,
+		Events:      extractEvents(timelineFor(doc, listItem), opts),
 	}
+	client.OrderEvents(data)
+	return data
 }
 
-func extractCountries(listItem *goquery.Selection) []string {
-	countryText := listItem.Find("small").Text()
-	if countryText == "" {
-		return []string{"Unknown", "Unknown"}
+// timelineFor returns the timeline items that belong to listItem. A timeline
+// nested in the list item wins; otherwise the detail pane is used, but only
+// when it describes this code: 4PX renders it for the selected item alone.
+func timelineFor(doc *goquery.Document, listItem *goquery.Selection) *goquery.Selection {
+	own := listItem.Find(".next-timeline-item")
+	if own.Length() > 0 {
+		return own
 	}
 
-	parts := strings.Split(countryText, " - ")
-	if len(parts) == 2 {
-		return []string{
-			mapCountryCode(strings.TrimSpace(parts[0])),
-			strings.TrimSpace(parts[1]),
-		}
+	if listItem.HasClass("next-list-item-selected") || doc.Find(".next-list-item").Length() == 1 {
+		return doc.Find(".next-timeline-item").Not(".next-list-item .next-timeline-item")
 	}
 
-	return []string{"Unknown", "Unknown"}
+	return own
 }
 
-func extractEvents(doc *goquery.Document) []models.Event {
+func extractCountries(listItem *goquery.Selection) (*models.Country, *models.Country) {
+	parts := strings.Split(listItem.Find("small").Text(), " - ")
+	if len(parts) != 2 {
+		return nil, nil
+	}
+
+	return resolveCountries(parts[0], parts[1])
+}
+
+func extractEvents(items *goquery.Selection, opts ParseOptions) []models.Event {
 	var events []models.Event
 
-	doc.Find(".next-timeline-item").Each(func(i int, s *goquery.Selection) {
-		event := extractTimelineEvent(s)
+	items.Each(func(i int, s *goquery.Selection) {
+		event := extractTimelineEvent(s, opts)
 		if event != nil {
 			events = append(events, *event)
 		}
@@ -75,7 +141,7 @@ func extractEvents(doc *goquery.Document) []models.Event {
 	return events
 }
 
-func extractTimelineEvent(timelineItem *goquery.Selection) *models.Event {
+func extractTimelineEvent(timelineItem *goquery.Selection, opts ParseOptions) *models.Event {
 	dateTimeText := timelineItem.Find(".next-timeline-item-left-content").Text()
 	dateTime := extractDateTime(dateTimeText)
 	if dateTime == "" {
@@ -88,73 +154,135 @@ func extractTimelineEvent(timelineItem *goquery.Selection) *models.Event {
 		return nil
 	}
 
-	return &models.Event{
+	loc := opts.Location
+	if offsetLoc := extractOffset(timelineItem.Text()); offsetLoc != nil {
+		loc = offsetLoc
+	}
+
+	event := newEvent(status, dateTime, loc, opts.DualTimes)
+	describeEvent(event, status)
+	return event
+}
+
+// newEvent builds an event from a wall-clock time shown in loc. When the time
+// cannot be parsed the raw text is kept as the date.
+func newEvent(status, dateTime string, loc *time.Location, dualTimes bool) *models.Event {
+	event := &models.Event{
 		Status: status,
-		Date:   parseDate(dateTime),
+		Date:   dateTime,
+	}
+
+	t, ok := parseDate(dateTime, loc)
+	if !ok {
+		return event
 	}
+
+	event.Date = t.Format(time.RFC3339)
+	if dualTimes {
+		event.DateUTC = t.UTC().Format(time.RFC3339)
+		event.DateLocal = t.Format("2006-01-02T15:04:05")
+	}
+
+	return event
 }
 
 func extractDateTime(text string) string {
-	re := regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
-	if match := re.FindString(text); match != "" {
+	if match := dateTimeRe.FindString(text); match != "" {
 		return match
 	}
 	return ""
 }
 
+// extractOffset finds a "UTC+08:00" style offset in text and returns it as a
+// fixed zone, or nil when the text has none.
+func extractOffset(text string) *time.Location {
+	match := utcOffsetRe.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+	if hours > 14 || minutes > 59 {
+		return nil
+	}
+
+	seconds := hours*60*60 + minutes*60
+	if match[1] == "-" {
+		seconds = -seconds
+	}
+
+	return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", match[1], hours, minutes), seconds)
+}
+
 func cleanStatusText(text string) string {
 	text = strings.TrimSpace(text)
 
-	text = strings.ReplaceAll(text, "UTC+08:00", "")
+	text = utcOffsetRe.ReplaceAllString(text, "")
 	text = strings.TrimSpace(text)
 
-	re := regexp.MustCompile(`\s+`)
-	text = re.ReplaceAllString(text, " ")
+	text = whitespaceRe.ReplaceAllString(text, " ")
 
 	return text
 }
 
-func mapCountryCode(countryName string) string {
-	countryMap := map[string]string{
-		"China":        "CN",
-		"Russian":      "RU",
-		"Russia":       "RU",
-		"Kazakhstan":   "KZ",
-		"Kazakh":       "KZ",
-		"Germany":      "DE",
-		"German":       "DE",
-		"USA":          "US",
-		"UnitedStates": "US",
-		"US":           "US",
+// resolveCountries maps both sides of a route; a route with a missing side
+// is treated as unknown altogether.
+func resolveCountries(origin, destination string) (*models.Country, *models.Country) {
+	origin = strings.TrimSpace(origin)
+	destination = strings.TrimSpace(destination)
+	if origin == "" || destination == "" {
+		return nil, nil
 	}
 
-	if code, exists := countryMap[countryName]; exists {
-		return code
-	}
+	return resolveCountry(origin), resolveCountry(destination)
+}
 
-	for name, code := range countryMap {
-		if strings.Contains(countryName, name) {
-			return code
-		}
+// resolveCountry looks the name up in the ISO 3166 table. Unmapped names are
+// logged, counted and returned with an empty code. Only the log has the name:
+// keyed by provider text, the metric would grow without bound.
+func resolveCountry(name string) *models.Country {
+	if c, ok := country.Resolve(name); ok {
+		return &models.Country{Code: c.Code, Name: c.Name}
 	}
 
-	if len(countryName) == 2 {
-		return strings.ToUpper(countryName)
+	log.Printf("client.ResolveCountry.Unmapped: %q", name)
+	metrics.CountryUnmapped.Add(1)
+	return &models.Country{Name: name}
+}
+
+// countryCodes builds the legacy two-element countries list.
+func countryCodes(origin, destination *models.Country) []string {
+	if origin == nil || destination == nil {
+		return []string{"Unknown", "Unknown"}
 	}
 
-	return countryName
+	codes := make([]string, 0, 2)
+	for _, c := range []*models.Country{origin, destination} {
+		if c.Code != "" {
+			codes = append(codes, c.Code)
+		} else {
+			codes = append(codes, c.Name)
+		}
+	}
+	return codes
 }
 
-func parseDate(dateStr string) string {
+// parseDate interprets a wall-clock time as shown in loc.
+func parseDate(dateStr string, loc *time.Location) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
 	formats := []string{
 		"2006-01-02 15:04:05",
 		"2006-01-02 15:04",
 	}
 
 	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t.Format(time.RFC3339)
+		if t, err := time.ParseInLocation(format, dateStr, loc); err == nil {
+			return t, true
 		}
 	}
-	return dateStr
+	return time.Time{}, false
 }
@@ -0,0 +1,271 @@
+package fourpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// Golden files are regenerated with:
+//
+//	go test ./internal/client/fourpx -run TestParseHTMLGolden -update
+//
+// A live page is captured into a new fixture (Chrome and network required) with:
+//
+//	go test ./internal/client/fourpx -run TestRecordFixture -record -record.name=<name> -record.codes=<code1,code2>
+var (
+	update      = flag.Bool("update", false, "rewrite golden files with the current parser output")
+	record      = flag.Bool("record", false, "capture a live 4PX page into testdata")
+	recordName  = flag.String("record.name", "", "fixture name for -record")
+	recordCodes = flag.String("record.codes", "", "comma-separated track codes for -record")
+	recordURL   = flag.String("record.url", "https://track.4px.com", "4PX base URL for -record")
+)
+
+const testdataDir = "testdata"
+
+// goldenParseOptions mirror the production defaults with dual times switched on,
+// so goldens cover every event field.
+var goldenParseOptions = ParseOptions{
+	Location:  time.FixedZone("UTC+08:00", 8*60*60),
+	DualTimes: true,
+}
+
+type goldenOutput struct {
+	Results map[string]*models.TrackData `json:"results"`
+	Signals client.LayoutSignals         `json:"signals"`
+}
+
+// TestParseHTMLGolden - прогон парсера по всем HTML-фикстурам и сравнение с golden-файлами
+func TestParseHTMLGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join(testdataDir, "*.html"))
+	if err != nil {
+		t.Fatalf("Failed to list fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("No fixtures found in testdata")
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			htmlContent, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			trackCodes := readCodes(t, filepath.Join(testdataDir, name+".codes"))
+
+			results, signals, err := ParseHTML(string(htmlContent), trackCodes, goldenParseOptions)
+			if err != nil {
+				t.Fatalf("ParseHTML failed: %v", err)
+			}
+
+			got, err := json.MarshalIndent(goldenOutput{Results: results, Signals: signals}, "", "  ")
+			if err != nil {
+				t.Fatalf("Failed to marshal results: %v", err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join(testdataDir, name+".golden.json")
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("ParseHTML output differs from %s (run with -update to accept)\n--- got ---\n%s\n--- want ---\n%s",
+					goldenPath, got, want)
+			}
+		})
+	}
+}
+
+// TestRecordFixture - сохранение живой страницы 4PX в новую фикстуру
+func TestRecordFixture(t *testing.T) {
+	if !*record {
+		t.Skip("run with -record to capture a live page")
+	}
+	if *recordName == "" || *recordCodes == "" {
+		t.Fatal("-record.name and -record.codes are required")
+	}
+
+	trackCodes := strings.Split(*recordCodes, ",")
+	c := NewFourPXClient(config.ExternalConfig{
+		BaseURL:     *recordURL,
+		HashPattern: "/#/result/0/",
+	}, nil, nil, false).(*FourPXClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	page, err := c.scrapeWithChromedp(ctx, trackCodes, nil)
+	if err != nil {
+		t.Fatalf("Failed to capture page: %v", err)
+	}
+
+	base := filepath.Join(testdataDir, *recordName)
+	if err := os.WriteFile(base+".html", []byte(page.html), 0o644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(base+".codes", []byte(strings.Join(trackCodes, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write codes: %v", err)
+	}
+
+	t.Logf("Recorded %s.html, run TestParseHTMLGolden with -update to create its golden file", base)
+}
+
+// TestExtractCountries - разбор строки "Origin - Destination"
+func TestExtractCountries(t *testing.T) {
+	tests := []struct {
+		name      string
+		small     string
+		want      []string
+		wantNames []string
+	}{
+		{name: "english names", small: "China - Russia", want: []string{"CN", "RU"}, wantNames: []string{"China", "Russia"}},
+		{name: "aliases", small: "Kazakh - USA", want: []string{"KZ", "US"}, wantNames: []string{"Kazakhstan", "United States"}},
+		{name: "alpha-2", small: "de - fr", want: []string{"DE", "FR"}, wantNames: []string{"Germany", "France"}},
+		{name: "chinese and russian", small: "中国 - Россия", want: []string{"CN", "RU"}, wantNames: []string{"China", "Russia"}},
+		{name: "unmapped kept", small: "Atlantis - Russia", want: []string{"Atlantis", "RU"}, wantNames: []string{"Atlantis", "Russia"}},
+		{name: "no separator", small: "China", want: []string{"Unknown", "Unknown"}},
+		{name: "empty", small: "", want: []string{"Unknown", "Unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+				`<li class="next-list-item"><small>` + tt.small + `</small></li>`))
+			if err != nil {
+				t.Fatalf("Failed to build document: %v", err)
+			}
+
+			origin, destination := extractCountries(doc.Find(".next-list-item"))
+			got := countryCodes(origin, destination)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("extractCountries(%q) codes = %v, want %v", tt.small, got, tt.want)
+			}
+
+			if tt.wantNames == nil {
+				if origin != nil || destination != nil {
+					t.Errorf("extractCountries(%q) = %v, %v, want nil", tt.small, origin, destination)
+				}
+				return
+			}
+			if origin.Name != tt.wantNames[0] || destination.Name != tt.wantNames[1] {
+				t.Errorf("extractCountries(%q) names = %q, %q, want %v", tt.small, origin.Name, destination.Name, tt.wantNames)
+			}
+		})
+	}
+}
+
+// TestParseDate - разбор локального времени страницы в заданной зоне
+func TestParseDate(t *testing.T) {
+	shanghai := time.FixedZone("UTC+08:00", 8*60*60)
+
+	tests := []struct {
+		in     string
+		loc    *time.Location
+		want   string
+		wantOK bool
+	}{
+		{in: "2025-09-18 11:53:58", loc: shanghai, want: "2025-09-18T11:53:58+08:00", wantOK: true},
+		{in: "2025-09-18 11:53", loc: shanghai, want: "2025-09-18T11:53:00+08:00", wantOK: true},
+		{in: "2025-09-18 11:53:58", loc: nil, want: "2025-09-18T11:53:58Z", wantOK: true},
+		{in: "yesterday", loc: shanghai, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseDate(tt.in, tt.loc)
+		if ok != tt.wantOK {
+			t.Errorf("parseDate(%q) ok = %v, want %v", tt.in, ok, tt.wantOK)
+			continue
+		}
+		if ok && got.Format(time.RFC3339) != tt.want {
+			t.Errorf("parseDate(%q) = %s, want %s", tt.in, got.Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+// TestExtractOffset - извлечение смещения UTC из текста события
+func TestExtractOffset(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantOffset int
+		wantFound  bool
+	}{
+		{in: "Departed UTC+08:00", wantOffset: 8 * 60 * 60, wantFound: true},
+		{in: "UTC-05:30 Arrived", wantOffset: -(5*60*60 + 30*60), wantFound: true},
+		{in: "UTC+3", wantOffset: 3 * 60 * 60, wantFound: true},
+		{in: "no offset here", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		loc := extractOffset(tt.in)
+		if (loc != nil) != tt.wantFound {
+			t.Errorf("extractOffset(%q) found = %v, want %v", tt.in, loc != nil, tt.wantFound)
+			continue
+		}
+		if loc == nil {
+			continue
+		}
+		if _, offset := time.Date(2025, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != tt.wantOffset {
+			t.Errorf("extractOffset(%q) offset = %d, want %d", tt.in, offset, tt.wantOffset)
+		}
+	}
+}
+
+// TestCleanStatusText - очистка текста статуса
+func TestCleanStatusText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "  Parcel information received  ", want: "Parcel information received"},
+		{in: "SYSTEM /\n\t Shipment arrived", want: "SYSTEM / Shipment arrived"},
+		{in: "Departed UTC+08:00", want: "Departed"},
+		{in: "Arrived UTC-05:00", want: "Arrived"},
+		{in: "UTC+08:00", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := cleanStatusText(tt.in); got != tt.want {
+			t.Errorf("cleanStatusText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func readCodes(t *testing.T, path string) []string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read track codes: %v", err)
+	}
+
+	var codes []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			codes = append(codes, line)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,63 @@
+package fourpx
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// TestResourceFilterShouldBlock - блокировка по типу ресурса и URL, разрешения важнее запретов
+func TestResourceFilterShouldBlock(t *testing.T) {
+	f := newResourceFilter(
+		[]string{"image", "Font", "unknown"},
+		[]string{"google-analytics", "/ADS/"},
+		[]string{"track.4px.com/static/logo"},
+	)
+
+	tests := []struct {
+		name         string
+		resourceType network.ResourceType
+		url          string
+		want         bool
+	}{
+		{"blocked type", network.ResourceTypeImage, "https://cdn.example.com/banner.png", true},
+		{"blocked type case-insensitive config", network.ResourceTypeFont, "https://cdn.example.com/font.woff2", true},
+		{"allowed type", network.ResourceTypeScript, "https://track.4px.com/app.js", false},
+		{"blocked url pattern", network.ResourceTypeScript, "https://www.google-analytics.com/analytics.js", true},
+		{"url pattern case-insensitive", network.ResourceTypeXHR, "https://track.4px.com/ads/pixel", true},
+		{"allow beats blocked type", network.ResourceTypeImage, "https://TRACK.4px.com/static/logo.png", false},
+		{"allow beats blocked url", network.ResourceTypeScript, "https://track.4px.com/static/logo/ads/x.js", false},
+		{"document never blocked", network.ResourceTypeDocument, "https://track.4px.com/ads/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.shouldBlock(tt.resourceType, tt.url); got != tt.want {
+				t.Errorf("shouldBlock(%s, %q) = %v, want %v", tt.resourceType, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestResourceFilterEnabled - фильтр выключен без типов и шаблонов блокировки
+func TestResourceFilterEnabled(t *testing.T) {
+	tests := []struct {
+		name                  string
+		types, blocks, allows []string
+		want                  bool
+	}{
+		{name: "empty", want: false},
+		{name: "allow only", allows: []string{"4px"}, want: false},
+		{name: "unknown type only", types: []string{"hologram"}, want: false},
+		{name: "types", types: []string{"media"}, want: true},
+		{name: "patterns", blocks: []string{"ads"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newResourceFilter(tt.types, tt.blocks, tt.allows).enabled(); got != tt.want {
+				t.Errorf("enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
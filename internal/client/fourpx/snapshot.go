@@ -0,0 +1,69 @@
+package fourpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+const (
+	snapshotReasonScrapeError = "scrape_error"
+	snapshotReasonBlocked     = "blocked"
+	snapshotReasonParseError  = "parse_error"
+	snapshotReasonEmptyResult = "empty_result"
+)
+
+type scrapedPage struct {
+	url        string
+	html       string
+	screenshot []byte
+}
+
+// snapshotRecorder persists pages of failed or suspicious scrapes. A nil
+// recorder does nothing, so callers do not need to check whether snapshots are
+// enabled.
+type snapshotRecorder struct {
+	store       repository.SnapshotRepository
+	screenshots bool
+}
+
+func (r *snapshotRecorder) record(batchID string, trackCodes []string, reason string, page *scrapedPage) {
+	if r == nil || page == nil || (page.html == "" && len(page.screenshot) == 0) {
+		return
+	}
+
+	snapshot := &models.Snapshot{
+		ID:            newID(),
+		BatchID:       batchID,
+		TrackCodes:    append([]string(nil), trackCodes...),
+		Reason:        reason,
+		URL:           page.url,
+		CreatedAt:     time.Now(),
+		HTMLSize:      len(page.html),
+		HasScreenshot: len(page.screenshot) > 0,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := r.store.Save(ctx, snapshot, page.html, page.screenshot); err != nil {
+			log.Printf("client.snapshotRecorder.SaveError: batch %s: %v", batchID, err)
+			return
+		}
+		log.Printf("client.snapshotRecorder.Saved: snapshot %s for batch %s (%s)", snapshot.ID, batchID, reason)
+	}()
+}
+
+func newID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
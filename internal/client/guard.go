@@ -0,0 +1,119 @@
+package client
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+)
+
+// ProviderStats is the provider health summary reported on /health.
+type ProviderStats struct {
+	Provider      string           `json:"provider"`
+	Blocked       map[string]int64 `json:"blocked"`
+	LastBlockKind string           `json:"last_block_kind,omitempty"`
+	LastBlockAt   *time.Time       `json:"last_block_at,omitempty"`
+	BackoffUntil  *time.Time       `json:"backoff_until,omitempty"`
+	Drift         *DriftStatus     `json:"drift,omitempty"`
+}
+
+// StatsReporter is implemented by clients that track provider health.
+type StatsReporter interface {
+	ProviderStats() ProviderStats
+}
+
+// ProviderGuard backs off from a provider after it served a block page. Each
+// consecutive block doubles the pause, starting at base and capped at max; a
+// successful scrape resets it.
+type ProviderGuard struct {
+	provider string
+	base     time.Duration
+	max      time.Duration
+
+	mu          sync.Mutex
+	level       int
+	until       time.Time
+	counts      map[erors.BlockKind]int64
+	lastKind    erors.BlockKind
+	lastBlockAt time.Time
+}
+
+func NewProviderGuard(provider string, base, max time.Duration) *ProviderGuard {
+	return &ProviderGuard{
+		provider: provider,
+		base:     base,
+		max:      max,
+		counts:   make(map[erors.BlockKind]int64),
+	}
+}
+
+// Check returns a ProviderBlockedError while the provider is in backoff.
+func (g *ProviderGuard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	remaining := time.Until(g.until)
+	if remaining <= 0 {
+		return nil
+	}
+
+	err := erors.NewProviderBlockedError(g.provider, g.lastKind, "backoff in progress")
+	err.RetryAfter = remaining
+	return err
+}
+
+func (g *ProviderGuard) RecordBlock(blockedErr *erors.ProviderBlockedError) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pause := g.base << g.level
+	if pause > g.max || pause <= 0 {
+		pause = g.max
+	} else {
+		g.level++
+	}
+
+	now := time.Now()
+	g.until = now.Add(pause)
+	g.counts[blockedErr.Kind]++
+	g.lastKind = blockedErr.Kind
+	g.lastBlockAt = now
+	blockedErr.RetryAfter = pause
+
+	metrics.ProviderBlocked.Add(g.provider+":"+string(blockedErr.Kind), 1)
+	log.Printf("client.ProviderGuard.Backoff: %s %s page (%s), pausing for %v",
+		g.provider, blockedErr.Kind, blockedErr.Signature, pause)
+}
+
+func (g *ProviderGuard) RecordSuccess() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.level = 0
+}
+
+func (g *ProviderGuard) Stats() ProviderStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := ProviderStats{
+		Provider:      g.provider,
+		Blocked:       make(map[string]int64, len(g.counts)),
+		LastBlockKind: string(g.lastKind),
+	}
+	for kind, count := range g.counts {
+		stats.Blocked[string(kind)] = count
+	}
+	if !g.lastBlockAt.IsZero() {
+		lastBlockAt := g.lastBlockAt
+		stats.LastBlockAt = &lastBlockAt
+	}
+	if time.Now().Before(g.until) {
+		until := g.until
+		stats.BackoffUntil = &until
+	}
+
+	return stats
+}
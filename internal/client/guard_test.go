@@ -0,0 +1,51 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/erors"
+)
+
+// TestProviderGuardBackoff - пауза удваивается с каждой блокировкой, ограничена max и сбрасывается успехом
+func TestProviderGuardBackoff(t *testing.T) {
+	guard := NewProviderGuard("test", time.Minute, 5*time.Minute)
+
+	if err := guard.Check(); err != nil {
+		t.Fatalf("fresh guard refused: %v", err)
+	}
+
+	record := func() time.Duration {
+		blockedErr := erors.NewProviderBlockedError("test", erors.BlockChallenge, "captcha")
+		guard.RecordBlock(blockedErr)
+		return blockedErr.RetryAfter
+	}
+
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := record(); got != want {
+			t.Errorf("block %d paused for %s, want %s", i+1, got, want)
+		}
+	}
+
+	err := guard.Check()
+	blockedErr, ok := erors.AsProviderBlocked(err)
+	if !ok {
+		t.Fatalf("Check during backoff = %v, want a blocked error", err)
+	}
+	if blockedErr.RetryAfter <= 4*time.Minute || blockedErr.RetryAfter > 5*time.Minute {
+		t.Errorf("Check RetryAfter = %s, want the rest of the 5m pause", blockedErr.RetryAfter)
+	}
+	if blockedErr.Kind != erors.BlockChallenge {
+		t.Errorf("Check kind = %s, want the last block kind", blockedErr.Kind)
+	}
+
+	guard.RecordSuccess()
+	if got := record(); got != time.Minute {
+		t.Errorf("block after success paused for %s, want base %s", got, time.Minute)
+	}
+
+	stats := guard.Stats()
+	if stats.Blocked[string(erors.BlockChallenge)] != 6 || stats.BackoffUntil == nil || stats.LastBlockAt == nil {
+		t.Errorf("stats = %+v", stats)
+	}
+}
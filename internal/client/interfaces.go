@@ -9,4 +9,6 @@ import (
 type ExternalAPIClient interface {
 	TrackPackage(ctx context.Context, trackCode string) (*models.TrackData, error)
 	TrackPackagesBatch(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error)
+	// Close aborts in-flight lookups; later lookups fail.
+	Close() error
 }
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// Lifetime ends the in-flight calls of a client when the client is closed.
+type Lifetime struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func NewLifetime() *Lifetime {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifetime{ctx: ctx, cancel: cancel}
+}
+
+// Bind returns a copy of ctx that is also cancelled when the lifetime ends.
+// Calls bound after Close fail right away.
+func (l *Lifetime) Bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(l.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (l *Lifetime) Close() {
+	l.once.Do(l.cancel)
+}
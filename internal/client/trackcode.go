@@ -0,0 +1,26 @@
+package client
+
+// IsValidTrackCode reports whether trackCode looks like a parcel code: 8 to 20
+// ASCII letters and digits with at least one of each. Codes are used in
+// provider URLs and Redis keys, so nothing else is accepted.
+func IsValidTrackCode(trackCode string) bool {
+	if len(trackCode) < 8 || len(trackCode) > 20 {
+		return false
+	}
+
+	hasLetter := false
+	hasDigit := false
+
+	for _, char := range trackCode {
+		switch {
+		case (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z'):
+			hasLetter = true
+		case char >= '0' && char <= '9':
+			hasDigit = true
+		default:
+			return false
+		}
+	}
+
+	return hasLetter && hasDigit
+}
@@ -0,0 +1,27 @@
+package client
+
+import "testing"
+
+// TestIsValidTrackCode - длина, буквы с цифрами и запрет посторонних символов
+func TestIsValidTrackCode(t *testing.T) {
+	tests := map[string]bool{
+		"LK517880262CN":         true,
+		"4PX3001234567890CN":    true,
+		"lk517880262cn":         true,
+		"":                      false,
+		"due":                   false,
+		"AB12345":               false,
+		"ABCDEFGHIJ":            false,
+		"1234567890":            false,
+		"LK517880262CN12345678": false,
+		"LK517880:lease":        false,
+		"LK5178 80262CN":        false,
+		"LK517880262CN\n":       false,
+	}
+
+	for code, want := range tests {
+		if got := IsValidTrackCode(code); got != want {
+			t.Errorf("IsValidTrackCode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that stands still until Advance moves it. Timers and
+// tickers fire from Advance, in deadline order, so tests decide exactly when
+// time-driven work happens.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+}
+
+// NewFake returns a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+func (f *Fake) Until(t time.Time) time.Duration { return t.Sub(f.Now()) }
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.newTimer(d, 0)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.newTimer(d, d)}
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d and fires every timer due by then.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+	for {
+		due := f.nextDueLocked(target)
+		if due == nil {
+			break
+		}
+		f.now = due.deadline
+		due.fireLocked()
+	}
+	f.now = target
+	f.changed.Broadcast()
+}
+
+// BlockUntil waits until n timers or tickers are armed, so a test can
+// advance the clock only after the code under test started waiting.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+func (f *Fake) newTimer(d, period time.Duration) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), period: period}
+	t.armLocked(d)
+	return t
+}
+
+func (f *Fake) nextDueLocked(target time.Time) *fakeTimer {
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	if len(f.timers) == 0 || f.timers[0].deadline.After(target) {
+		return nil
+	}
+	return f.timers[0]
+}
+
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, armed := range f.timers {
+		if armed == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.clock.removeLocked(t)
+	t.armLocked(d)
+	return active
+}
+
+func (t *fakeTimer) armLocked(d time.Duration) {
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.changed.Broadcast()
+	if d <= 0 && t.period == 0 {
+		t.fireLocked()
+	}
+}
+
+// fireLocked sends the time without blocking, dropping it when the last one
+// was not read yet, as time.Timer does, and re-arms tickers.
+func (t *fakeTimer) fireLocked() {
+	t.clock.removeLocked(t)
+	select {
+	case t.c <- t.clock.now:
+	default:
+	}
+	if t.period > 0 {
+		t.deadline = t.deadline.Add(t.period)
+		t.clock.timers = append(t.clock.timers, t)
+	}
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
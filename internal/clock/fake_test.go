@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// TestFakeTimer - таймер срабатывает только после Advance до его срока
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(start)
+	timer := f.NewTimer(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	if _, ok := fired(timer.C()); ok {
+		t.Fatal("timer fired early")
+	}
+
+	f.Advance(time.Millisecond)
+	if at, ok := fired(timer.C()); !ok || !at.Equal(start.Add(time.Second)) {
+		t.Fatalf("timer fired = %v at %s, want at %s", ok, at, start.Add(time.Second))
+	}
+
+	if timer.Reset(time.Minute) {
+		t.Error("Reset of a fired timer reported it active")
+	}
+	if !timer.Stop() {
+		t.Error("Stop of an armed timer reported it inactive")
+	}
+	f.Advance(time.Hour)
+	if _, ok := fired(timer.C()); ok {
+		t.Error("stopped timer fired")
+	}
+}
+
+// TestFakeTicker - тикер срабатывает каждый период, лишние тики отбрасываются
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(start)
+	ticker := f.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		f.Advance(time.Minute)
+		if at, ok := fired(ticker.C()); !ok || !at.Equal(start.Add(time.Duration(i)*time.Minute)) {
+			t.Fatalf("tick %d = %v at %s", i, ok, at)
+		}
+	}
+
+	f.Advance(10 * time.Minute)
+	if _, ok := fired(ticker.C()); !ok {
+		t.Fatal("no tick after a long advance")
+	}
+	if _, ok := fired(ticker.C()); ok {
+		t.Error("ticker queued more than one tick")
+	}
+}
+
+// TestFakeBlockUntil - BlockUntil ждет, пока горутина заведет таймер
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(start)
+	done := make(chan struct{})
+	go func() {
+		<-f.After(time.Second)
+		close(done)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("After did not fire")
+	}
+}
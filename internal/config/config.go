@@ -3,15 +3,28 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	defaultBlockedResourceTypes = []string{"Image", "Font", "Media", "Stylesheet"}
+	defaultBlockedURLPatterns   = []string{
+		"google-analytics.com",
+		"googletagmanager.com",
+		"doubleclick.net",
+		"hm.baidu.com",
+		"cnzz.com",
+	}
+)
+
 func Load() (*Config, error) {
 	config := &Config{
 		Server: ServerConfig{
 			Port:         getEnv("SERVER_PORT", "8080"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			AdminToken:   getEnv("ADMIN_TOKEN", ""),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -25,11 +38,95 @@ func Load() (*Config, error) {
 			HashPattern: getEnv("EXTERNAL_API_HASH_PATTERN", "/#/result/0/"),
 			Timeout:     getDurationEnv("EXTERNAL_API_TIMEOUT", 60*time.Second),
 			RetryCount:  getIntEnv("EXTERNAL_API_RETRY_COUNT", 3),
+			Mode:        getEnv("EXTERNAL_API_MODE", ExternalModeBrowser),
+			APIPath:     getEnv("EXTERNAL_API_TRACK_PATH", "/track/v2/front/listTrackV3"),
+			Language:    getEnv("EXTERNAL_API_LANGUAGE", "en-us"),
+
+			DefaultTimezone: getEnv("EXTERNAL_DEFAULT_TIMEZONE", "Asia/Shanghai"),
+			DualTimes:       getBoolEnv("EXTERNAL_EVENT_DUAL_TIMES", false),
+
+			MaxCodesPerPage:  getIntEnv("EXTERNAL_MAX_CODES_PER_PAGE", 10),
+			MaxURLLength:     getIntEnv("EXTERNAL_MAX_URL_LENGTH", 2000),
+			ChunkConcurrency: getIntEnv("EXTERNAL_CHUNK_CONCURRENCY", 2),
+
+			BackoffBase: getDurationEnv("EXTERNAL_BACKOFF_BASE", 30*time.Second),
+			BackoffMax:  getDurationEnv("EXTERNAL_BACKOFF_MAX", 15*time.Minute),
+
+			DriftWindow:     getIntEnv("EXTERNAL_DRIFT_WINDOW", 20),
+			DriftThreshold:  getFloatEnv("EXTERNAL_DRIFT_THRESHOLD", 0.8),
+			DriftMinSamples: getIntEnv("EXTERNAL_DRIFT_MIN_SAMPLES", 5),
+
+			BlockResourceTypes: getListEnv("EXTERNAL_BLOCK_RESOURCE_TYPES", defaultBlockedResourceTypes),
+			BlockURLPatterns:   getListEnv("EXTERNAL_BLOCK_URL_PATTERNS", defaultBlockedURLPatterns),
+			AllowURLPatterns:   getListEnv("EXTERNAL_ALLOW_URL_PATTERNS", nil),
+		},
+		Proxy: ProxyConfig{
+			URLs:          getListEnv("PROXY_POOL", nil),
+			MaxFailures:   getIntEnv("PROXY_MAX_FAILURES", 3),
+			EjectDuration: getDurationEnv("PROXY_EJECT_DURATION", 5*time.Minute),
+			SlowThreshold: getDurationEnv("PROXY_SLOW_THRESHOLD", 20*time.Second),
+		},
+		Snapshot: SnapshotConfig{
+			Store:      getEnv("SNAPSHOT_STORE", ""),
+			Dir:        getEnv("SNAPSHOT_DIR", "/tmp/track-snapshots"),
+			MaxCount:   getIntEnv("SNAPSHOT_MAX_COUNT", 200),
+			Retention:  getDurationEnv("SNAPSHOT_RETENTION", 72*time.Hour),
+			Screenshot: getBoolEnv("SNAPSHOT_SCREENSHOT", true),
+		},
+		History: HistoryConfig{
+			Driver: getEnv("HISTORY_DRIVER", ""),
+			DSN:    getEnv("HISTORY_DSN", "/tmp/track-history.db"),
+		},
+		Changes: ChangesConfig{
+			Enabled:    getBoolEnv("CHANGES_ENABLED", true),
+			Retention:  getDurationEnv("CHANGES_RETENTION", 30*24*time.Hour),
+			MaxPerCode: getIntEnv("CHANGES_MAX_PER_CODE", 100),
 		},
 		Batcher: BatcherConfig{
 			BatchSize:    getIntEnv("BATCH_SIZE", 50),
 			BatchTimeout: getDurationEnv("BATCH_FLUSH_TIMEOUT", 2*time.Second),
 			Workers:      getIntEnv("BATCH_WORKERS", 3),
+
+			InteractiveTimeout: getDurationEnv("BATCH_INTERACTIVE_TIMEOUT", 200*time.Millisecond),
+			StarvationTimeout:  getDurationEnv("BATCH_STARVATION_TIMEOUT", 30*time.Second),
+			InteractiveWeight:  getIntEnv("BATCH_WEIGHT_INTERACTIVE", 6),
+			NormalWeight:       getIntEnv("BATCH_WEIGHT_NORMAL", 3),
+			BulkWeight:         getIntEnv("BATCH_WEIGHT_BULK", 1),
+
+			QueueCapacity:  getIntEnv("BATCH_QUEUE_CAPACITY", 100),
+			EnqueueTimeout: getDurationEnv("BATCH_ENQUEUE_TIMEOUT", 200*time.Millisecond),
+			RetryAfter:     getDurationEnv("BATCH_RETRY_AFTER", time.Second),
+			OverflowPolicy: getEnv("BATCH_OVERFLOW_POLICY", OverflowBlock),
+			BatchDeadline:  getDurationEnv("BATCH_DEADLINE", 2*time.Minute),
+			DrainTimeout:   getDurationEnv("BATCH_DRAIN_TIMEOUT", 20*time.Second),
+			CacheTTL:       getDurationEnv("BATCH_CACHE_TTL", 5*time.Minute),
+			DurableQueue:   getBoolEnv("BATCH_DURABLE_QUEUE", false),
+
+			Adaptive:        getBoolEnv("BATCH_ADAPTIVE", false),
+			MinBatchSize:    getIntEnv("BATCH_MIN_SIZE", 5),
+			MaxBatchSize:    getIntEnv("BATCH_MAX_SIZE", 50),
+			MinBatchTimeout: getDurationEnv("BATCH_MIN_FLUSH_TIMEOUT", 200*time.Millisecond),
+			MaxBatchTimeout: getDurationEnv("BATCH_MAX_FLUSH_TIMEOUT", 5*time.Second),
+			TargetLatency:   getDurationEnv("BATCH_TARGET_LATENCY", 10*time.Second),
+			MaxErrorRate:    getFloatEnv("BATCH_MAX_ERROR_RATE", 0.2),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:      getBoolEnv("SCHEDULER_ENABLED", false),
+			TickInterval: getDurationEnv("SCHEDULER_TICK_INTERVAL", 30*time.Second),
+			BatchLimit:   getIntEnv("SCHEDULER_BATCH_LIMIT", 100),
+			MinInterval:  getDurationEnv("SCHEDULER_MIN_INTERVAL", time.Hour),
+			MaxInterval:  getDurationEnv("SCHEDULER_MAX_INTERVAL", 12*time.Hour),
+			MaxAge:       getDurationEnv("SCHEDULER_MAX_AGE", 60*24*time.Hour),
+			LeaseTTL:     getDurationEnv("SCHEDULER_LEASE_TTL", 5*time.Minute),
+			Jitter:       getFloatEnv("SCHEDULER_JITTER", 0.2),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:        getBoolEnv("RATE_LIMIT_ENABLED", false),
+			PagesPerMinute: getFloatEnv("RATE_LIMIT_PAGES_PER_MINUTE", 60),
+			Burst:          getIntEnv("RATE_LIMIT_BURST", 10),
+			MaxSessions:    getIntEnv("RATE_LIMIT_MAX_SESSIONS", 6),
+			SessionTTL:     getDurationEnv("RATE_LIMIT_SESSION_TTL", 2*time.Minute),
+			Providers:      getProviderLimitsEnv("RATE_LIMIT_PROVIDERS"),
 		},
 	}
 
@@ -40,13 +137,23 @@ type Config struct {
 	Server   ServerConfig   `json:"server"`
 	Redis    RedisConfig    `json:"redis"`
 	External ExternalConfig `json:"external"`
+	Proxy    ProxyConfig    `json:"proxy"`
+	Snapshot SnapshotConfig `json:"snapshot"`
+	History  HistoryConfig  `json:"history"`
+	Changes  ChangesConfig  `json:"changes"`
 	Batcher  BatcherConfig  `json:"batcher"`
+
+	Scheduler SchedulerConfig `json:"scheduler"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
 }
 
 type ServerConfig struct {
 	Port         string        `json:"port"`
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
+	// AdminToken guards the /admin routes, passed as a bearer token; the
+	// routes are not served while it is empty.
+	AdminToken string `json:"-"`
 }
 
 type RedisConfig struct {
@@ -61,13 +168,185 @@ type BatcherConfig struct {
 	BatchSize    int           `json:"batch_size"`
 	BatchTimeout time.Duration `json:"batch_timeout"`
 	Workers      int           `json:"workers"`
+
+	// InteractiveTimeout is the flush timeout for interactive requests;
+	// other lanes wait up to BatchTimeout.
+	InteractiveTimeout time.Duration `json:"interactive_timeout"`
+	// StarvationTimeout puts requests waiting that long at the head of the
+	// next batch regardless of their lane.
+	StarvationTimeout time.Duration `json:"starvation_timeout"`
+	// Lane weights set how many requests of each lane are taken per round
+	// when a batch is assembled.
+	InteractiveWeight int `json:"interactive_weight"`
+	NormalWeight      int `json:"normal_weight"`
+	BulkWeight        int `json:"bulk_weight"`
+
+	// QueueCapacity is the number of requests each lane buffers before
+	// callers have to wait; zero means twice the batch size.
+	QueueCapacity int `json:"queue_capacity"`
+	// EnqueueTimeout is how long a caller waits for room in a full queue
+	// before it is turned away.
+	EnqueueTimeout time.Duration `json:"enqueue_timeout"`
+	// RetryAfter is the hint returned to callers turned away by a full queue.
+	RetryAfter time.Duration `json:"retry_after"`
+	// OverflowPolicy decides what happens to a batch when every worker is
+	// busy and the worker queue is full.
+	OverflowPolicy string `json:"overflow_policy"`
+	// BatchDeadline bounds a whole batch scrape, retries included.
+	BatchDeadline time.Duration `json:"batch_deadline"`
+	// DrainTimeout bounds how long shutdown waits for in-flight batches.
+	DrainTimeout time.Duration `json:"drain_timeout"`
+	// CacheTTL is how long fetched tracking data stays in the cache.
+	CacheTTL time.Duration `json:"cache_ttl"`
+	// DurableQueue saves requests left at shutdown to Redis and fetches them
+	// after the next start.
+	DurableQueue bool `json:"durable_queue"`
+
+	// Adaptive lets the batcher move the batch size and the flush timeout
+	// within the bounds below, starting from BatchSize and BatchTimeout.
+	Adaptive        bool          `json:"adaptive"`
+	MinBatchSize    int           `json:"min_batch_size"`
+	MaxBatchSize    int           `json:"max_batch_size"`
+	MinBatchTimeout time.Duration `json:"min_batch_timeout"`
+	MaxBatchTimeout time.Duration `json:"max_batch_timeout"`
+	// TargetLatency and MaxErrorRate are the averages above which batches
+	// are made smaller.
+	TargetLatency time.Duration `json:"target_latency"`
+	MaxErrorRate  float64       `json:"max_error_rate"`
+}
+
+// Overflow policies for BatcherConfig.OverflowPolicy.
+const (
+	// OverflowBlock holds the batch until a worker frees up; new requests
+	// back up in the lane queues meanwhile.
+	OverflowBlock = "block"
+	// OverflowRejectOldest drops the longest queued batch to make room.
+	OverflowRejectOldest = "reject_oldest"
+	// OverflowRejectNewest drops the batch that did not fit.
+	OverflowRejectNewest = "reject_newest"
+	// OverflowMerge appends the batch to one already waiting for a worker.
+	OverflowMerge = "merge"
+)
+
+type ProxyConfig struct {
+	URLs          []string      `json:"-"`
+	MaxFailures   int           `json:"max_failures"`
+	EjectDuration time.Duration `json:"eject_duration"`
+	SlowThreshold time.Duration `json:"slow_threshold"`
+}
+
+const (
+	SnapshotStoreFile  = "file"
+	SnapshotStoreRedis = "redis"
+)
+
+// SnapshotConfig controls storing pages of failed scrapes. An empty Store
+// disables snapshots.
+type SnapshotConfig struct {
+	Store      string        `json:"store"`
+	Dir        string        `json:"dir"`
+	MaxCount   int           `json:"max_count"`
+	Retention  time.Duration `json:"retention"`
+	Screenshot bool          `json:"screenshot"`
+}
+
+const (
+	HistoryDriverSQLite   = "sqlite"
+	HistoryDriverPostgres = "postgres"
+)
+
+// HistoryConfig selects the SQL database that keeps every event ever seen per
+// track code. An empty Driver disables history.
+type HistoryConfig struct {
+	Driver string `json:"driver"`
+	DSN    string `json:"-"`
+}
+
+// ChangesConfig controls diffing every scrape against the previous one. The
+// previous state and the changes are kept in Redis for Retention.
+type ChangesConfig struct {
+	Enabled    bool          `json:"enabled"`
+	Retention  time.Duration `json:"retention"`
+	MaxPerCode int           `json:"max_per_code"`
+}
+
+// SchedulerConfig controls background refreshing of watched track codes. The
+// refresh interval grows with the time since the last event, between
+// MinInterval and MaxInterval, and is randomized by ±Jitter.
+type SchedulerConfig struct {
+	Enabled      bool          `json:"enabled"`
+	TickInterval time.Duration `json:"tick_interval"`
+	BatchLimit   int           `json:"batch_limit"`
+	MinInterval  time.Duration `json:"min_interval"`
+	MaxInterval  time.Duration `json:"max_interval"`
+	MaxAge       time.Duration `json:"max_age"`
+	LeaseTTL     time.Duration `json:"lease_ttl"`
+	Jitter       float64       `json:"jitter"`
+}
+
+// RateLimitConfig caps how hard all replicas together hit each provider: a
+// token bucket shared through Redis limits pages per minute and a pool of
+// session slots limits pages scraped at once. Providers overrides the
+// defaults per provider; zero disables a limit.
+type RateLimitConfig struct {
+	Enabled        bool    `json:"enabled"`
+	PagesPerMinute float64 `json:"pages_per_minute"`
+	Burst          int     `json:"burst"`
+	MaxSessions    int     `json:"max_sessions"`
+	// SessionTTL frees the slot of a replica that died mid-scrape.
+	SessionTTL time.Duration            `json:"session_ttl"`
+	Providers  map[string]ProviderLimit `json:"providers,omitempty"`
+}
+
+type ProviderLimit struct {
+	PagesPerMinute float64 `json:"pages_per_minute"`
+	Burst          int     `json:"burst"`
+	MaxSessions    int     `json:"max_sessions"`
+}
+
+// Limit returns the limits of provider.
+func (c RateLimitConfig) Limit(provider string) ProviderLimit {
+	if limit, ok := c.Providers[provider]; ok {
+		return limit
+	}
+	return ProviderLimit{
+		PagesPerMinute: c.PagesPerMinute,
+		Burst:          c.Burst,
+		MaxSessions:    c.MaxSessions,
+	}
 }
 
+const (
+	ExternalModeBrowser = "browser"
+	ExternalModeHTTP    = "http"
+)
+
 type ExternalConfig struct {
 	BaseURL     string        `json:"base_url"`
 	HashPattern string        `json:"hash_pattern"`
 	Timeout     time.Duration `json:"timeout"`
 	RetryCount  int           `json:"retry_count"`
+	Mode        string        `json:"mode"`
+	APIPath     string        `json:"api_path"`
+	Language    string        `json:"language"`
+
+	DefaultTimezone string `json:"default_timezone"`
+	DualTimes       bool   `json:"dual_times"`
+
+	MaxCodesPerPage  int `json:"max_codes_per_page"`
+	MaxURLLength     int `json:"max_url_length"`
+	ChunkConcurrency int `json:"chunk_concurrency"`
+
+	BackoffBase time.Duration `json:"backoff_base"`
+	BackoffMax  time.Duration `json:"backoff_max"`
+
+	DriftWindow     int     `json:"drift_window"`
+	DriftThreshold  float64 `json:"drift_threshold"`
+	DriftMinSamples int     `json:"drift_min_samples"`
+
+	BlockResourceTypes []string `json:"block_resource_types"`
+	BlockURLPatterns   []string `json:"block_url_patterns"`
+	AllowURLPatterns   []string `json:"allow_url_patterns"`
 }
 
 func getEnv(key, defaultValue string) string {
@@ -86,6 +365,24 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -94,3 +391,53 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getListEnv reads a comma-separated list. An explicitly empty variable yields
+// an empty list, so defaults can be switched off.
+func getListEnv(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getProviderLimitsEnv reads a comma-separated list of
+// provider:pages_per_minute:burst:max_sessions entries. Malformed entries are
+// skipped.
+func getProviderLimitsEnv(key string) map[string]ProviderLimit {
+	limits := make(map[string]ProviderLimit)
+	for _, entry := range getListEnv(key, nil) {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 4 || fields[0] == "" {
+			continue
+		}
+
+		pages, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		burst, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		sessions, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+
+		limits[fields[0]] = ProviderLimit{
+			PagesPerMinute: pages,
+			Burst:          burst,
+			MaxSessions:    sessions,
+		}
+	}
+	return limits
+}
@@ -0,0 +1,93 @@
+// Package country resolves free-form country names found on carrier pages to
+// ISO 3166-1 alpha-2 codes.
+package country
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Country is one ISO 3166-1 entry. Name is the English display name.
+type Country struct {
+	Code   string
+	Name   string
+	NameZH string
+	NameRU string
+}
+
+// minContainsLen keeps short keys such as "us" or "uk" from matching inside
+// unrelated words during the substring fallback.
+const minContainsLen = 4
+
+var (
+	byCode = make(map[string]Country, len(countries))
+	byName = make(map[string]string, len(countries)*4+len(aliases))
+)
+
+func init() {
+	for _, c := range countries {
+		byCode[c.Code] = c
+		for _, name := range []string{c.Code, c.Name, c.NameZH, c.NameRU} {
+			byName[normalize(name)] = c.Code
+		}
+	}
+	for alias, code := range aliases {
+		byName[normalize(alias)] = code
+	}
+}
+
+// ByCode returns the entry for an alpha-2 code.
+func ByCode(code string) (Country, bool) {
+	c, ok := byCode[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Resolve maps a country name, alias or alpha-2 code in English, Chinese or
+// Russian to its ISO entry. When there is no exact match the longest known
+// name contained in the input wins, so "Mainland China Hub" resolves to CN.
+func Resolve(name string) (Country, bool) {
+	key := normalize(name)
+	if key == "" {
+		return Country{}, false
+	}
+
+	if code, ok := byName[key]; ok {
+		return byCode[code], true
+	}
+
+	padded := " " + key + " "
+	best := ""
+	for candidate := range byName {
+		if utf8.RuneCountInString(candidate) < minContainsLen && !isHan(candidate) {
+			continue
+		}
+		if len(candidate) < len(best) || (len(candidate) == len(best) && candidate >= best) {
+			continue
+		}
+		if strings.Contains(padded, " "+candidate+" ") || (isHan(candidate) && strings.Contains(key, candidate)) {
+			best = candidate
+		}
+	}
+	if best == "" {
+		return Country{}, false
+	}
+
+	return byCode[byName[best]], true
+}
+
+// normalize lowercases the name and collapses punctuation and whitespace into
+// single spaces.
+func normalize(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// isHan reports whether s is written in Chinese characters, which carry no
+// word separators and are matched as plain substrings.
+func isHan(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.Is(unicode.Han, r)
+}
@@ -0,0 +1,62 @@
+package country
+
+import "testing"
+
+// TestResolve - сопоставление названий стран с кодами ISO 3166-1
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{in: "China", want: "CN", wantOK: true},
+		{in: "  russian federation ", want: "RU", wantOK: true},
+		{in: "UnitedStates", want: "US", wantOK: true},
+		{in: "U.S.A.", want: "US", wantOK: true},
+		{in: "gb", want: "GB", wantOK: true},
+		{in: "德国", want: "DE", wantOK: true},
+		{in: "Казахстан", want: "KZ", wantOK: true},
+		{in: "Côte d'Ivoire", want: "CI", wantOK: true},
+		{in: "Mainland China Hub", want: "CN", wantOK: true},
+		{in: "Papua New Guinea", want: "PG", wantOK: true},
+		{in: "中国香港", want: "HK", wantOK: true},
+		{in: "Russian Post", want: "RU", wantOK: true},
+		{in: "Atlantis", wantOK: false},
+		{in: "Bus", wantOK: false},
+		{in: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Resolve(tt.in)
+		if ok != tt.wantOK {
+			t.Errorf("Resolve(%q) ok = %v, want %v", tt.in, ok, tt.wantOK)
+			continue
+		}
+		if ok && got.Code != tt.want {
+			t.Errorf("Resolve(%q) = %s, want %s", tt.in, got.Code, tt.want)
+		}
+	}
+}
+
+// TestTableConsistency - коды в таблице и алиасах уникальны и известны
+func TestTableConsistency(t *testing.T) {
+	seen := make(map[string]bool, len(countries))
+	for _, c := range countries {
+		if len(c.Code) != 2 || seen[c.Code] {
+			t.Errorf("invalid or duplicate code %q", c.Code)
+		}
+		seen[c.Code] = true
+		if c.Name == "" || c.NameZH == "" || c.NameRU == "" {
+			t.Errorf("%s: missing name", c.Code)
+		}
+	}
+	if len(countries) != 249 {
+		t.Errorf("table has %d entries, want 249", len(countries))
+	}
+
+	for alias, code := range aliases {
+		if !seen[code] {
+			t.Errorf("alias %q points to unknown code %s", alias, code)
+		}
+	}
+}
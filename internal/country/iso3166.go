@@ -0,0 +1,347 @@
+package country
+
+// countries is the ISO 3166-1 alpha-2 table with English, Chinese and Russian
+// short names.
+var countries = []Country{
+	{Code: "AD", Name: "Andorra", NameZH: "安道尔", NameRU: "Андорра"},
+	{Code: "AE", Name: "United Arab Emirates", NameZH: "阿联酋", NameRU: "Объединённые Арабские Эмираты"},
+	{Code: "AF", Name: "Afghanistan", NameZH: "阿富汗", NameRU: "Афганистан"},
+	{Code: "AG", Name: "Antigua and Barbuda", NameZH: "安提瓜和巴布达", NameRU: "Антигуа и Барбуда"},
+	{Code: "AI", Name: "Anguilla", NameZH: "安圭拉", NameRU: "Ангилья"},
+	{Code: "AL", Name: "Albania", NameZH: "阿尔巴尼亚", NameRU: "Албания"},
+	{Code: "AM", Name: "Armenia", NameZH: "亚美尼亚", NameRU: "Армения"},
+	{Code: "AO", Name: "Angola", NameZH: "安哥拉", NameRU: "Ангола"},
+	{Code: "AQ", Name: "Antarctica", NameZH: "南极洲", NameRU: "Антарктида"},
+	{Code: "AR", Name: "Argentina", NameZH: "阿根廷", NameRU: "Аргентина"},
+	{Code: "AS", Name: "American Samoa", NameZH: "美属萨摩亚", NameRU: "Американское Самоа"},
+	{Code: "AT", Name: "Austria", NameZH: "奥地利", NameRU: "Австрия"},
+	{Code: "AU", Name: "Australia", NameZH: "澳大利亚", NameRU: "Австралия"},
+	{Code: "AW", Name: "Aruba", NameZH: "阿鲁巴", NameRU: "Аруба"},
+	{Code: "AX", Name: "Åland Islands", NameZH: "奥兰群岛", NameRU: "Аландские острова"},
+	{Code: "AZ", Name: "Azerbaijan", NameZH: "阿塞拜疆", NameRU: "Азербайджан"},
+	{Code: "BA", Name: "Bosnia and Herzegovina", NameZH: "波斯尼亚和黑塞哥维那", NameRU: "Босния и Герцеговина"},
+	{Code: "BB", Name: "Barbados", NameZH: "巴巴多斯", NameRU: "Барбадос"},
+	{Code: "BD", Name: "Bangladesh", NameZH: "孟加拉国", NameRU: "Бангладеш"},
+	{Code: "BE", Name: "Belgium", NameZH: "比利时", NameRU: "Бельгия"},
+	{Code: "BF", Name: "Burkina Faso", NameZH: "布基纳法索", NameRU: "Буркина-Фасо"},
+	{Code: "BG", Name: "Bulgaria", NameZH: "保加利亚", NameRU: "Болгария"},
+	{Code: "BH", Name: "Bahrain", NameZH: "巴林", NameRU: "Бахрейн"},
+	{Code: "BI", Name: "Burundi", NameZH: "布隆迪", NameRU: "Бурунди"},
+	{Code: "BJ", Name: "Benin", NameZH: "贝宁", NameRU: "Бенин"},
+	{Code: "BL", Name: "Saint Barthélemy", NameZH: "圣巴泰勒米", NameRU: "Сен-Бартелеми"},
+	{Code: "BM", Name: "Bermuda", NameZH: "百慕大", NameRU: "Бермуды"},
+	{Code: "BN", Name: "Brunei Darussalam", NameZH: "文莱", NameRU: "Бруней"},
+	{Code: "BO", Name: "Bolivia", NameZH: "玻利维亚", NameRU: "Боливия"},
+	{Code: "BQ", Name: "Bonaire, Sint Eustatius and Saba", NameZH: "荷兰加勒比区", NameRU: "Бонайре, Синт-Эстатиус и Саба"},
+	{Code: "BR", Name: "Brazil", NameZH: "巴西", NameRU: "Бразилия"},
+	{Code: "BS", Name: "Bahamas", NameZH: "巴哈马", NameRU: "Багамы"},
+	{Code: "BT", Name: "Bhutan", NameZH: "不丹", NameRU: "Бутан"},
+	{Code: "BV", Name: "Bouvet Island", NameZH: "布韦岛", NameRU: "Остров Буве"},
+	{Code: "BW", Name: "Botswana", NameZH: "博茨瓦纳", NameRU: "Ботсвана"},
+	{Code: "BY", Name: "Belarus", NameZH: "白俄罗斯", NameRU: "Беларусь"},
+	{Code: "BZ", Name: "Belize", NameZH: "伯利兹", NameRU: "Белиз"},
+	{Code: "CA", Name: "Canada", NameZH: "加拿大", NameRU: "Канада"},
+	{Code: "CC", Name: "Cocos (Keeling) Islands", NameZH: "科科斯（基林）群岛", NameRU: "Кокосовые острова"},
+	{Code: "CD", Name: "Congo, Democratic Republic of the", NameZH: "刚果（金）", NameRU: "Демократическая Республика Конго"},
+	{Code: "CF", Name: "Central African Republic", NameZH: "中非", NameRU: "Центральноафриканская Республика"},
+	{Code: "CG", Name: "Congo", NameZH: "刚果（布）", NameRU: "Республика Конго"},
+	{Code: "CH", Name: "Switzerland", NameZH: "瑞士", NameRU: "Швейцария"},
+	{Code: "CI", Name: "Côte d'Ivoire", NameZH: "科特迪瓦", NameRU: "Кот-д’Ивуар"},
+	{Code: "CK", Name: "Cook Islands", NameZH: "库克群岛", NameRU: "Острова Кука"},
+	{Code: "CL", Name: "Chile", NameZH: "智利", NameRU: "Чили"},
+	{Code: "CM", Name: "Cameroon", NameZH: "喀麦隆", NameRU: "Камерун"},
+	{Code: "CN", Name: "China", NameZH: "中国", NameRU: "Китай"},
+	{Code: "CO", Name: "Colombia", NameZH: "哥伦比亚", NameRU: "Колумбия"},
+	{Code: "CR", Name: "Costa Rica", NameZH: "哥斯达黎加", NameRU: "Коста-Рика"},
+	{Code: "CU", Name: "Cuba", NameZH: "古巴", NameRU: "Куба"},
+	{Code: "CV", Name: "Cabo Verde", NameZH: "佛得角", NameRU: "Кабо-Верде"},
+	{Code: "CW", Name: "Curaçao", NameZH: "库拉索", NameRU: "Кюрасао"},
+	{Code: "CX", Name: "Christmas Island", NameZH: "圣诞岛", NameRU: "Остров Рождества"},
+	{Code: "CY", Name: "Cyprus", NameZH: "塞浦路斯", NameRU: "Кипр"},
+	{Code: "CZ", Name: "Czechia", NameZH: "捷克", NameRU: "Чехия"},
+	{Code: "DE", Name: "Germany", NameZH: "德国", NameRU: "Германия"},
+	{Code: "DJ", Name: "Djibouti", NameZH: "吉布提", NameRU: "Джибути"},
+	{Code: "DK", Name: "Denmark", NameZH: "丹麦", NameRU: "Дания"},
+	{Code: "DM", Name: "Dominica", NameZH: "多米尼克", NameRU: "Доминика"},
+	{Code: "DO", Name: "Dominican Republic", NameZH: "多米尼加", NameRU: "Доминиканская Республика"},
+	{Code: "DZ", Name: "Algeria", NameZH: "阿尔及利亚", NameRU: "Алжир"},
+	{Code: "EC", Name: "Ecuador", NameZH: "厄瓜多尔", NameRU: "Эквадор"},
+	{Code: "EE", Name: "Estonia", NameZH: "爱沙尼亚", NameRU: "Эстония"},
+	{Code: "EG", Name: "Egypt", NameZH: "埃及", NameRU: "Египет"},
+	{Code: "EH", Name: "Western Sahara", NameZH: "西撒哈拉", NameRU: "Западная Сахара"},
+	{Code: "ER", Name: "Eritrea", NameZH: "厄立特里亚", NameRU: "Эритрея"},
+	{Code: "ES", Name: "Spain", NameZH: "西班牙", NameRU: "Испания"},
+	{Code: "ET", Name: "Ethiopia", NameZH: "埃塞俄比亚", NameRU: "Эфиопия"},
+	{Code: "FI", Name: "Finland", NameZH: "芬兰", NameRU: "Финляндия"},
+	{Code: "FJ", Name: "Fiji", NameZH: "斐济", NameRU: "Фиджи"},
+	{Code: "FK", Name: "Falkland Islands (Malvinas)", NameZH: "福克兰群岛", NameRU: "Фолклендские острова"},
+	{Code: "FM", Name: "Micronesia", NameZH: "密克罗尼西亚联邦", NameRU: "Микронезия"},
+	{Code: "FO", Name: "Faroe Islands", NameZH: "法罗群岛", NameRU: "Фарерские острова"},
+	{Code: "FR", Name: "France", NameZH: "法国", NameRU: "Франция"},
+	{Code: "GA", Name: "Gabon", NameZH: "加蓬", NameRU: "Габон"},
+	{Code: "GB", Name: "United Kingdom", NameZH: "英国", NameRU: "Великобритания"},
+	{Code: "GD", Name: "Grenada", NameZH: "格林纳达", NameRU: "Гренада"},
+	{Code: "GE", Name: "Georgia", NameZH: "格鲁吉亚", NameRU: "Грузия"},
+	{Code: "GF", Name: "French Guiana", NameZH: "法属圭亚那", NameRU: "Французская Гвиана"},
+	{Code: "GG", Name: "Guernsey", NameZH: "根西", NameRU: "Гернси"},
+	{Code: "GH", Name: "Ghana", NameZH: "加纳", NameRU: "Гана"},
+	{Code: "GI", Name: "Gibraltar", NameZH: "直布罗陀", NameRU: "Гибралтар"},
+	{Code: "GL", Name: "Greenland", NameZH: "格陵兰", NameRU: "Гренландия"},
+	{Code: "GM", Name: "Gambia", NameZH: "冈比亚", NameRU: "Гамбия"},
+	{Code: "GN", Name: "Guinea", NameZH: "几内亚", NameRU: "Гвинея"},
+	{Code: "GP", Name: "Guadeloupe", NameZH: "瓜德罗普", NameRU: "Гваделупа"},
+	{Code: "GQ", Name: "Equatorial Guinea", NameZH: "赤道几内亚", NameRU: "Экваториальная Гвинея"},
+	{Code: "GR", Name: "Greece", NameZH: "希腊", NameRU: "Греция"},
+	{Code: "GS", Name: "South Georgia and the South Sandwich Islands", NameZH: "南乔治亚和南桑威奇群岛", NameRU: "Южная Георгия и Южные Сандвичевы острова"},
+	{Code: "GT", Name: "Guatemala", NameZH: "危地马拉", NameRU: "Гватемала"},
+	{Code: "GU", Name: "Guam", NameZH: "关岛", NameRU: "Гуам"},
+	{Code: "GW", Name: "Guinea-Bissau", NameZH: "几内亚比绍", NameRU: "Гвинея-Бисау"},
+	{Code: "GY", Name: "Guyana", NameZH: "圭亚那", NameRU: "Гайана"},
+	{Code: "HK", Name: "Hong Kong", NameZH: "香港", NameRU: "Гонконг"},
+	{Code: "HM", Name: "Heard Island and McDonald Islands", NameZH: "赫德岛和麦克唐纳群岛", NameRU: "Остров Херд и острова Макдональд"},
+	{Code: "HN", Name: "Honduras", NameZH: "洪都拉斯", NameRU: "Гондурас"},
+	{Code: "HR", Name: "Croatia", NameZH: "克罗地亚", NameRU: "Хорватия"},
+	{Code: "HT", Name: "Haiti", NameZH: "海地", NameRU: "Гаити"},
+	{Code: "HU", Name: "Hungary", NameZH: "匈牙利", NameRU: "Венгрия"},
+	{Code: "ID", Name: "Indonesia", NameZH: "印度尼西亚", NameRU: "Индонезия"},
+	{Code: "IE", Name: "Ireland", NameZH: "爱尔兰", NameRU: "Ирландия"},
+	{Code: "IL", Name: "Israel", NameZH: "以色列", NameRU: "Израиль"},
+	{Code: "IM", Name: "Isle of Man", NameZH: "马恩岛", NameRU: "Остров Мэн"},
+	{Code: "IN", Name: "India", NameZH: "印度", NameRU: "Индия"},
+	{Code: "IO", Name: "British Indian Ocean Territory", NameZH: "英属印度洋领地", NameRU: "Британская территория в Индийском океане"},
+	{Code: "IQ", Name: "Iraq", NameZH: "伊拉克", NameRU: "Ирак"},
+	{Code: "IR", Name: "Iran", NameZH: "伊朗", NameRU: "Иран"},
+	{Code: "IS", Name: "Iceland", NameZH: "冰岛", NameRU: "Исландия"},
+	{Code: "IT", Name: "Italy", NameZH: "意大利", NameRU: "Италия"},
+	{Code: "JE", Name: "Jersey", NameZH: "泽西", NameRU: "Джерси"},
+	{Code: "JM", Name: "Jamaica", NameZH: "牙买加", NameRU: "Ямайка"},
+	{Code: "JO", Name: "Jordan", NameZH: "约旦", NameRU: "Иордания"},
+	{Code: "JP", Name: "Japan", NameZH: "日本", NameRU: "Япония"},
+	{Code: "KE", Name: "Kenya", NameZH: "肯尼亚", NameRU: "Кения"},
+	{Code: "KG", Name: "Kyrgyzstan", NameZH: "吉尔吉斯斯坦", NameRU: "Киргизия"},
+	{Code: "KH", Name: "Cambodia", NameZH: "柬埔寨", NameRU: "Камбоджа"},
+	{Code: "KI", Name: "Kiribati", NameZH: "基里巴斯", NameRU: "Кирибати"},
+	{Code: "KM", Name: "Comoros", NameZH: "科摩罗", NameRU: "Коморы"},
+	{Code: "KN", Name: "Saint Kitts and Nevis", NameZH: "圣基茨和尼维斯", NameRU: "Сент-Китс и Невис"},
+	{Code: "KP", Name: "North Korea", NameZH: "朝鲜", NameRU: "КНДР"},
+	{Code: "KR", Name: "South Korea", NameZH: "韩国", NameRU: "Республика Корея"},
+	{Code: "KW", Name: "Kuwait", NameZH: "科威特", NameRU: "Кувейт"},
+	{Code: "KY", Name: "Cayman Islands", NameZH: "开曼群岛", NameRU: "Острова Кайман"},
+	{Code: "KZ", Name: "Kazakhstan", NameZH: "哈萨克斯坦", NameRU: "Казахстан"},
+	{Code: "LA", Name: "Laos", NameZH: "老挝", NameRU: "Лаос"},
+	{Code: "LB", Name: "Lebanon", NameZH: "黎巴嫩", NameRU: "Ливан"},
+	{Code: "LC", Name: "Saint Lucia", NameZH: "圣卢西亚", NameRU: "Сент-Люсия"},
+	{Code: "LI", Name: "Liechtenstein", NameZH: "列支敦士登", NameRU: "Лихтенштейн"},
+	{Code: "LK", Name: "Sri Lanka", NameZH: "斯里兰卡", NameRU: "Шри-Ланка"},
+	{Code: "LR", Name: "Liberia", NameZH: "利比里亚", NameRU: "Либерия"},
+	{Code: "LS", Name: "Lesotho", NameZH: "莱索托", NameRU: "Лесото"},
+	{Code: "LT", Name: "Lithuania", NameZH: "立陶宛", NameRU: "Литва"},
+	{Code: "LU", Name: "Luxembourg", NameZH: "卢森堡", NameRU: "Люксембург"},
+	{Code: "LV", Name: "Latvia", NameZH: "拉脱维亚", NameRU: "Латвия"},
+	{Code: "LY", Name: "Libya", NameZH: "利比亚", NameRU: "Ливия"},
+	{Code: "MA", Name: "Morocco", NameZH: "摩洛哥", NameRU: "Марокко"},
+	{Code: "MC", Name: "Monaco", NameZH: "摩纳哥", NameRU: "Монако"},
+	{Code: "MD", Name: "Moldova", NameZH: "摩尔多瓦", NameRU: "Молдова"},
+	{Code: "ME", Name: "Montenegro", NameZH: "黑山", NameRU: "Черногория"},
+	{Code: "MF", Name: "Saint Martin (French part)", NameZH: "法属圣马丁", NameRU: "Сен-Мартен"},
+	{Code: "MG", Name: "Madagascar", NameZH: "马达加斯加", NameRU: "Мадагаскар"},
+	{Code: "MH", Name: "Marshall Islands", NameZH: "马绍尔群岛", NameRU: "Маршалловы Острова"},
+	{Code: "MK", Name: "North Macedonia", NameZH: "北马其顿", NameRU: "Северная Македония"},
+	{Code: "ML", Name: "Mali", NameZH: "马里", NameRU: "Мали"},
+	{Code: "MM", Name: "Myanmar", NameZH: "缅甸", NameRU: "Мьянма"},
+	{Code: "MN", Name: "Mongolia", NameZH: "蒙古", NameRU: "Монголия"},
+	{Code: "MO", Name: "Macao", NameZH: "澳门", NameRU: "Макао"},
+	{Code: "MP", Name: "Northern Mariana Islands", NameZH: "北马里亚纳群岛", NameRU: "Северные Марианские острова"},
+	{Code: "MQ", Name: "Martinique", NameZH: "马提尼克", NameRU: "Мартиника"},
+	{Code: "MR", Name: "Mauritania", NameZH: "毛里塔尼亚", NameRU: "Мавритания"},
+	{Code: "MS", Name: "Montserrat", NameZH: "蒙特塞拉特", NameRU: "Монтсеррат"},
+	{Code: "MT", Name: "Malta", NameZH: "马耳他", NameRU: "Мальта"},
+	{Code: "MU", Name: "Mauritius", NameZH: "毛里求斯", NameRU: "Маврикий"},
+	{Code: "MV", Name: "Maldives", NameZH: "马尔代夫", NameRU: "Мальдивы"},
+	{Code: "MW", Name: "Malawi", NameZH: "马拉维", NameRU: "Малави"},
+	{Code: "MX", Name: "Mexico", NameZH: "墨西哥", NameRU: "Мексика"},
+	{Code: "MY", Name: "Malaysia", NameZH: "马来西亚", NameRU: "Малайзия"},
+	{Code: "MZ", Name: "Mozambique", NameZH: "莫桑比克", NameRU: "Мозамбик"},
+	{Code: "NA", Name: "Namibia", NameZH: "纳米比亚", NameRU: "Намибия"},
+	{Code: "NC", Name: "New Caledonia", NameZH: "新喀里多尼亚", NameRU: "Новая Каледония"},
+	{Code: "NE", Name: "Niger", NameZH: "尼日尔", NameRU: "Нигер"},
+	{Code: "NF", Name: "Norfolk Island", NameZH: "诺福克岛", NameRU: "Остров Норфолк"},
+	{Code: "NG", Name: "Nigeria", NameZH: "尼日利亚", NameRU: "Нигерия"},
+	{Code: "NI", Name: "Nicaragua", NameZH: "尼加拉瓜", NameRU: "Никарагуа"},
+	{Code: "NL", Name: "Netherlands", NameZH: "荷兰", NameRU: "Нидерланды"},
+	{Code: "NO", Name: "Norway", NameZH: "挪威", NameRU: "Норвегия"},
+	{Code: "NP", Name: "Nepal", NameZH: "尼泊尔", NameRU: "Непал"},
+	{Code: "NR", Name: "Nauru", NameZH: "瑙鲁", NameRU: "Науру"},
+	{Code: "NU", Name: "Niue", NameZH: "纽埃", NameRU: "Ниуэ"},
+	{Code: "NZ", Name: "New Zealand", NameZH: "新西兰", NameRU: "Новая Зеландия"},
+	{Code: "OM", Name: "Oman", NameZH: "阿曼", NameRU: "Оман"},
+	{Code: "PA", Name: "Panama", NameZH: "巴拿马", NameRU: "Панама"},
+	{Code: "PE", Name: "Peru", NameZH: "秘鲁", NameRU: "Перу"},
+	{Code: "PF", Name: "French Polynesia", NameZH: "法属波利尼西亚", NameRU: "Французская Полинезия"},
+	{Code: "PG", Name: "Papua New Guinea", NameZH: "巴布亚新几内亚", NameRU: "Папуа — Новая Гвинея"},
+	{Code: "PH", Name: "Philippines", NameZH: "菲律宾", NameRU: "Филиппины"},
+	{Code: "PK", Name: "Pakistan", NameZH: "巴基斯坦", NameRU: "Пакистан"},
+	{Code: "PL", Name: "Poland", NameZH: "波兰", NameRU: "Польша"},
+	{Code: "PM", Name: "Saint Pierre and Miquelon", NameZH: "圣皮埃尔和密克隆", NameRU: "Сен-Пьер и Микелон"},
+	{Code: "PN", Name: "Pitcairn", NameZH: "皮特凯恩群岛", NameRU: "Острова Питкэрн"},
+	{Code: "PR", Name: "Puerto Rico", NameZH: "波多黎各", NameRU: "Пуэрто-Рико"},
+	{Code: "PS", Name: "Palestine", NameZH: "巴勒斯坦", NameRU: "Палестина"},
+	{Code: "PT", Name: "Portugal", NameZH: "葡萄牙", NameRU: "Португалия"},
+	{Code: "PW", Name: "Palau", NameZH: "帕劳", NameRU: "Палау"},
+	{Code: "PY", Name: "Paraguay", NameZH: "巴拉圭", NameRU: "Парагвай"},
+	{Code: "QA", Name: "Qatar", NameZH: "卡塔尔", NameRU: "Катар"},
+	{Code: "RE", Name: "Réunion", NameZH: "留尼汪", NameRU: "Реюньон"},
+	{Code: "RO", Name: "Romania", NameZH: "罗马尼亚", NameRU: "Румыния"},
+	{Code: "RS", Name: "Serbia", NameZH: "塞尔维亚", NameRU: "Сербия"},
+	{Code: "RU", Name: "Russia", NameZH: "俄罗斯", NameRU: "Россия"},
+	{Code: "RW", Name: "Rwanda", NameZH: "卢旺达", NameRU: "Руанда"},
+	{Code: "SA", Name: "Saudi Arabia", NameZH: "沙特阿拉伯", NameRU: "Саудовская Аравия"},
+	{Code: "SB", Name: "Solomon Islands", NameZH: "所罗门群岛", NameRU: "Соломоновы Острова"},
+	{Code: "SC", Name: "Seychelles", NameZH: "塞舌尔", NameRU: "Сейшельские Острова"},
+	{Code: "SD", Name: "Sudan", NameZH: "苏丹", NameRU: "Судан"},
+	{Code: "SE", Name: "Sweden", NameZH: "瑞典", NameRU: "Швеция"},
+	{Code: "SG", Name: "Singapore", NameZH: "新加坡", NameRU: "Сингапур"},
+	{Code: "SH", Name: "Saint Helena, Ascension and Tristan da Cunha", NameZH: "圣赫勒拿", NameRU: "Остров Святой Елены"},
+	{Code: "SI", Name: "Slovenia", NameZH: "斯洛文尼亚", NameRU: "Словения"},
+	{Code: "SJ", Name: "Svalbard and Jan Mayen", NameZH: "斯瓦尔巴和扬马延", NameRU: "Шпицберген и Ян-Майен"},
+	{Code: "SK", Name: "Slovakia", NameZH: "斯洛伐克", NameRU: "Словакия"},
+	{Code: "SL", Name: "Sierra Leone", NameZH: "塞拉利昂", NameRU: "Сьерра-Леоне"},
+	{Code: "SM", Name: "San Marino", NameZH: "圣马力诺", NameRU: "Сан-Марино"},
+	{Code: "SN", Name: "Senegal", NameZH: "塞内加尔", NameRU: "Сенегал"},
+	{Code: "SO", Name: "Somalia", NameZH: "索马里", NameRU: "Сомали"},
+	{Code: "SR", Name: "Suriname", NameZH: "苏里南", NameRU: "Суринам"},
+	{Code: "SS", Name: "South Sudan", NameZH: "南苏丹", NameRU: "Южный Судан"},
+	{Code: "ST", Name: "Sao Tome and Principe", NameZH: "圣多美和普林西比", NameRU: "Сан-Томе и Принсипи"},
+	{Code: "SV", Name: "El Salvador", NameZH: "萨尔瓦多", NameRU: "Сальвадор"},
+	{Code: "SX", Name: "Sint Maarten (Dutch part)", NameZH: "荷属圣马丁", NameRU: "Синт-Мартен"},
+	{Code: "SY", Name: "Syria", NameZH: "叙利亚", NameRU: "Сирия"},
+	{Code: "SZ", Name: "Eswatini", NameZH: "斯威士兰", NameRU: "Эсватини"},
+	{Code: "TC", Name: "Turks and Caicos Islands", NameZH: "特克斯和凯科斯群岛", NameRU: "Теркс и Кайкос"},
+	{Code: "TD", Name: "Chad", NameZH: "乍得", NameRU: "Чад"},
+	{Code: "TF", Name: "French Southern Territories", NameZH: "法属南部领地", NameRU: "Французские Южные и Антарктические территории"},
+	{Code: "TG", Name: "Togo", NameZH: "多哥", NameRU: "Того"},
+	{Code: "TH", Name: "Thailand", NameZH: "泰国", NameRU: "Таиланд"},
+	{Code: "TJ", Name: "Tajikistan", NameZH: "塔吉克斯坦", NameRU: "Таджикистан"},
+	{Code: "TK", Name: "Tokelau", NameZH: "托克劳", NameRU: "Токелау"},
+	{Code: "TL", Name: "Timor-Leste", NameZH: "东帝汶", NameRU: "Восточный Тимор"},
+	{Code: "TM", Name: "Turkmenistan", NameZH: "土库曼斯坦", NameRU: "Туркмения"},
+	{Code: "TN", Name: "Tunisia", NameZH: "突尼斯", NameRU: "Тунис"},
+	{Code: "TO", Name: "Tonga", NameZH: "汤加", NameRU: "Тонга"},
+	{Code: "TR", Name: "Türkiye", NameZH: "土耳其", NameRU: "Турция"},
+	{Code: "TT", Name: "Trinidad and Tobago", NameZH: "特立尼达和多巴哥", NameRU: "Тринидад и Тобаго"},
+	{Code: "TV", Name: "Tuvalu", NameZH: "图瓦卢", NameRU: "Тувалу"},
+	{Code: "TW", Name: "Taiwan", NameZH: "台湾", NameRU: "Тайвань"},
+	{Code: "TZ", Name: "Tanzania", NameZH: "坦桑尼亚", NameRU: "Танзания"},
+	{Code: "UA", Name: "Ukraine", NameZH: "乌克兰", NameRU: "Украина"},
+	{Code: "UG", Name: "Uganda", NameZH: "乌干达", NameRU: "Уганда"},
+	{Code: "UM", Name: "United States Minor Outlying Islands", NameZH: "美国本土外小岛屿", NameRU: "Внешние малые острова США"},
+	{Code: "US", Name: "United States", NameZH: "美国", NameRU: "США"},
+	{Code: "UY", Name: "Uruguay", NameZH: "乌拉圭", NameRU: "Уругвай"},
+	{Code: "UZ", Name: "Uzbekistan", NameZH: "乌兹别克斯坦", NameRU: "Узбекистан"},
+	{Code: "VA", Name: "Holy See", NameZH: "梵蒂冈", NameRU: "Ватикан"},
+	{Code: "VC", Name: "Saint Vincent and the Grenadines", NameZH: "圣文森特和格林纳丁斯", NameRU: "Сент-Винсент и Гренадины"},
+	{Code: "VE", Name: "Venezuela", NameZH: "委内瑞拉", NameRU: "Венесуэла"},
+	{Code: "VG", Name: "Virgin Islands (British)", NameZH: "英属维尔京群岛", NameRU: "Британские Виргинские острова"},
+	{Code: "VI", Name: "Virgin Islands (U.S.)", NameZH: "美属维尔京群岛", NameRU: "Виргинские Острова (США)"},
+	{Code: "VN", Name: "Viet Nam", NameZH: "越南", NameRU: "Вьетнам"},
+	{Code: "VU", Name: "Vanuatu", NameZH: "瓦努阿图", NameRU: "Вануату"},
+	{Code: "WF", Name: "Wallis and Futuna", NameZH: "瓦利斯和富图纳", NameRU: "Уоллис и Футуна"},
+	{Code: "WS", Name: "Samoa", NameZH: "萨摩亚", NameRU: "Самоа"},
+	{Code: "YE", Name: "Yemen", NameZH: "也门", NameRU: "Йемен"},
+	{Code: "YT", Name: "Mayotte", NameZH: "马约特", NameRU: "Майотта"},
+	{Code: "ZA", Name: "South Africa", NameZH: "南非", NameRU: "Южно-Африканская Республика"},
+	{Code: "ZM", Name: "Zambia", NameZH: "赞比亚", NameRU: "Замбия"},
+	{Code: "ZW", Name: "Zimbabwe", NameZH: "津巴布韦", NameRU: "Зимбабве"},
+}
+
+// aliases maps alternative spellings seen on carrier pages to alpha-2 codes.
+var aliases = map[string]string{
+	"Russian Federation": "RU",
+	"Russian":            "RU",
+	"Российская Федерация": "RU",
+	"РФ":                       "RU",
+	"Kazakh":                   "KZ",
+	"Republic of Kazakhstan":   "KZ",
+	"German":                   "DE",
+	"Deutschland":              "DE",
+	"USA":                      "US",
+	"U.S.A.":                   "US",
+	"U.S.":                     "US",
+	"UnitedStates":             "US",
+	"United States of America": "US",
+	"America":                  "US",
+	"美利坚合众国":                   "US",
+	"Соединённые Штаты":        "US",
+	"Соединенные Штаты Америки": "US",
+	"UK":                                     "GB",
+	"Great Britain":                          "GB",
+	"England":                                "GB",
+	"Britain":                                "GB",
+	"英格兰":                                    "GB",
+	"Англия":                                 "GB",
+	"Mainland China":                         "CN",
+	"China Mainland":                         "CN",
+	"PRC":                                    "CN",
+	"People's Republic of China":             "CN",
+	"中国大陆":                                   "CN",
+	"КНР":                                    "CN",
+	"Hongkong":                               "HK",
+	"Hong Kong SAR":                          "HK",
+	"中国香港":                                   "HK",
+	"Macau":                                  "MO",
+	"中国澳门":                                   "MO",
+	"中国台湾":                                   "TW",
+	"Korea":                                  "KR",
+	"Korea, Republic of":                     "KR",
+	"Republic of Korea":                      "KR",
+	"South Korean":                           "KR",
+	"Южная Корея":                            "KR",
+	"Korea, Democratic People's Republic of": "KP",
+	"Северная Корея":                         "KP",
+	"Czech Republic":                         "CZ",
+	"Чешская Республика":                     "CZ",
+	"Turkey":                                 "TR",
+	"Holland":                                "NL",
+	"The Netherlands":                        "NL",
+	"Голландия":                              "NL",
+	"Vietnam":                                "VN",
+	"Ivory Coast":                            "CI",
+	"Cote d'Ivoire":                          "CI",
+	"Cape Verde":                             "CV",
+	"Swaziland":                              "SZ",
+	"Macedonia":                              "MK",
+	"Burma":                                  "MM",
+	"East Timor":                             "TL",
+	"Brunei":                                 "BN",
+	"Lao People's Democratic Republic":       "LA",
+	"Syrian Arab Republic":                   "SY",
+	"Iran, Islamic Republic of":              "IR",
+	"Moldova, Republic of":                   "MD",
+	"Tanzania, United Republic of":           "TZ",
+	"Bolivia, Plurinational State of":        "BO",
+	"Venezuela, Bolivarian Republic of":      "VE",
+	"Democratic Republic of the Congo":       "CD",
+	"DR Congo":                               "CD",
+	"Republic of the Congo":                  "CG",
+	"Vatican":                                "VA",
+	"Vatican City":                           "VA",
+	"UAE":                                    "AE",
+	"Emirates":                               "AE",
+	"ОАЭ":                                    "AE",
+	"Byelorussia":                            "BY",
+	"Белоруссия":                             "BY",
+	"Kyrgyz Republic":                        "KG",
+	"Кыргызстан":                             "KG",
+	"Туркменистан":                           "TM",
+	"Aland Islands":                          "AX",
+	"Reunion":                                "RE",
+	"Curacao":                                "CW",
+	"Saint Barthelemy":                       "BL",
+	"Falkland Islands":                       "FK",
+	"Micronesia, Federated States of":        "FM",
+	"Palestine, State of":                    "PS",
+	"Sao Tome":                               "ST",
+	"St. Lucia":                              "LC",
+	"St. Kitts and Nevis":                    "KN",
+	"St. Vincent and the Grenadines":         "VC",
+}
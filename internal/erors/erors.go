@@ -3,6 +3,7 @@ package erors
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 var (
@@ -86,3 +87,75 @@ func GetErrorCode(err error) string {
 	}
 	return "UNKNOWN"
 }
+
+var ErrProviderBlocked = errors.New("tracking provider blocked the request")
+
+type BlockKind string
+
+const (
+	BlockChallenge   BlockKind = "challenge"
+	BlockDenied      BlockKind = "blocked"
+	BlockMaintenance BlockKind = "maintenance"
+)
+
+// ProviderBlockedError is returned when the provider answers with an anti-bot
+// challenge, an access-denied or a maintenance page instead of tracking data.
+type ProviderBlockedError struct {
+	Provider   string
+	Kind       BlockKind
+	Signature  string
+	RetryAfter time.Duration
+}
+
+func (e *ProviderBlockedError) Error() string {
+	msg := fmt.Sprintf("%s: %s page detected", e.Provider, e.Kind)
+	if e.Signature != "" {
+		msg += fmt.Sprintf(" (%s)", e.Signature)
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter.Round(time.Second))
+	}
+	return msg
+}
+
+func (e *ProviderBlockedError) Unwrap() error {
+	return ErrProviderBlocked
+}
+
+func NewProviderBlockedError(provider string, kind BlockKind, signature string) *ProviderBlockedError {
+	return &ProviderBlockedError{
+		Provider:  provider,
+		Kind:      kind,
+		Signature: signature,
+	}
+}
+
+func IsProviderBlocked(err error) bool {
+	return errors.Is(err, ErrProviderBlocked)
+}
+
+func AsProviderBlocked(err error) (*ProviderBlockedError, bool) {
+	var blockedErr *ProviderBlockedError
+	if errors.As(err, &blockedErr) {
+		return blockedErr, true
+	}
+	return nil, false
+}
+
+// PartialBatchError is returned together with the results of a batch when some
+// of its codes could not be tracked. Failed maps every such code to its cause.
+type PartialBatchError struct {
+	Failed map[string]error
+}
+
+func (e *PartialBatchError) Error() string {
+	return fmt.Sprintf("%d track codes of the batch failed", len(e.Failed))
+}
+
+func AsPartialBatch(err error) (*PartialBatchError, bool) {
+	var partialErr *PartialBatchError
+	if errors.As(err, &partialErr) {
+		return partialErr, true
+	}
+	return nil, false
+}
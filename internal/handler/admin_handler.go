@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/proxypool"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+// snapshotIDPattern matches the ids snapshots are saved under. Other ids are
+// refused before they reach the store or a response header.
+var snapshotIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+type AdminHandler struct {
+	proxyPool *proxypool.Pool
+	snapshots repository.SnapshotRepository
+}
+
+func NewAdminHandler(proxyPool *proxypool.Pool, snapshots repository.SnapshotRepository) *AdminHandler {
+	return &AdminHandler{
+		proxyPool: proxyPool,
+		snapshots: snapshots,
+	}
+}
+
+func (h *AdminHandler) GetProxies(w http.ResponseWriter, r *http.Request) {
+	proxies := []proxypool.ProxyState{}
+	if h.proxyPool != nil {
+		proxies = h.proxyPool.Snapshot()
+	}
+
+	response := map[string]interface{}{
+		"status":  true,
+		"count":   len(proxies),
+		"proxies": proxies,
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if h.snapshots == nil {
+		h.writeErrorResponse(w, http.StatusNotFound, "snapshot store is not configured")
+		return
+	}
+
+	snapshots, err := h.snapshots.List(r.Context(), r.URL.Query().Get("batch_id"))
+	if err != nil {
+		log.Printf("handler.ListSnapshots.Error: %v", err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "failed to list snapshots")
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":    true,
+		"count":     len(snapshots),
+		"snapshots": snapshots,
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) GetSnapshotHTML(w http.ResponseWriter, r *http.Request) {
+	if h.snapshots == nil {
+		h.writeErrorResponse(w, http.StatusNotFound, "snapshot store is not configured")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if !snapshotIDPattern.MatchString(id) {
+		h.writeErrorResponse(w, http.StatusBadRequest, "invalid snapshot id")
+		return
+	}
+	content, err := h.snapshots.GetHTML(r.Context(), id)
+	if err != nil {
+		h.writeSnapshotError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", attachment(id+".html"))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(content))
+}
+
+func (h *AdminHandler) GetSnapshotScreenshot(w http.ResponseWriter, r *http.Request) {
+	if h.snapshots == nil {
+		h.writeErrorResponse(w, http.StatusNotFound, "snapshot store is not configured")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if !snapshotIDPattern.MatchString(id) {
+		h.writeErrorResponse(w, http.StatusBadRequest, "invalid snapshot id")
+		return
+	}
+	content, err := h.snapshots.GetScreenshot(r.Context(), id)
+	if err != nil {
+		h.writeSnapshotError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", attachment(id+".png"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func attachment(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+func (h *AdminHandler) writeSnapshotError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrSnapshotNotFound) {
+		h.writeErrorResponse(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+
+	log.Printf("handler.GetSnapshot.Error: %v", err)
+	h.writeErrorResponse(w, http.StatusInternalServerError, "failed to load snapshot")
+}
+
+func (h *AdminHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	response := models.TrackResponse{
+		Status: false,
+		Error:  message,
+	}
+
+	h.writeJSONResponse(w, statusCode, response)
+}
+
+func (h *AdminHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+	}
+}
@@ -2,14 +2,28 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/shamil/proxy_track_service-1/internal/batcher"
+	"github.com/shamil/proxy_track_service-1/internal/erors"
 	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
 	"github.com/shamil/proxy_track_service-1/internal/service"
 )
 
+// Event orders accepted in ?order=. Events are stored newest first.
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
 type TrackHandler struct {
 	trackingService service.TrackingService
 }
@@ -34,15 +48,37 @@ func (h *TrackHandler) GetTrackStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	responseChan := h.trackingService.TrackPackage(r.Context(), trackCode)
+	order := r.URL.Query().Get("order")
+	if order != "" && order != orderAsc && order != orderDesc {
+		h.writeErrorResponse(w, http.StatusBadRequest, "order must be asc or desc")
+		return
+	}
+
+	priority := batcher.PriorityInteractive
+	if value := r.URL.Query().Get("priority"); value != "" {
+		parsed, err := batcher.ParsePriority(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "priority must be interactive, normal or bulk")
+			return
+		}
+		priority = parsed
+	}
+
+	responseChan := h.trackingService.TrackPackage(r.Context(), trackCode, priority)
 
 	select {
 	case response := <-responseChan:
 		if !response.Status && response.Error != "" {
+			if response.RetryAfter > 0 {
+				w.Header().Set("Retry-After", retryAfterSeconds(response.RetryAfter))
+			}
 			statusCode := h.getStatusCodeFromError(response.Error)
 			h.writeErrorResponse(w, statusCode, response.Error)
 			return
 		}
+		if order == orderAsc {
+			response.Data = oldestFirst(response.Data)
+		}
 		h.writeJSONResponse(w, http.StatusOK, response)
 	case <-r.Context().Done():
 		h.writeErrorResponse(w, http.StatusRequestTimeout, "request cancelled by client")
@@ -50,6 +86,148 @@ func (h *TrackHandler) GetTrackStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *TrackHandler) GetTrackHistory(w http.ResponseWriter, r *http.Request) {
+	trackCode := strings.TrimSpace(mux.Vars(r)["trackCode"])
+	if trackCode == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "track_code is required")
+		return
+	}
+
+	history, err := h.trackingService.History(r.Context(), trackCode)
+	if errors.Is(err, service.ErrHistoryDisabled) {
+		h.writeErrorResponse(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("handler.GetTrackHistory.Error: %s: %v", trackCode, err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "failed to load tracking history")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":     true,
+		"track_code": trackCode,
+		"events":     history,
+	})
+}
+
+func (h *TrackHandler) GetTrackChanges(w http.ResponseWriter, r *http.Request) {
+	trackCode := strings.TrimSpace(mux.Vars(r)["trackCode"])
+	if trackCode == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "track_code is required")
+		return
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		since = parsed
+	}
+
+	changes, err := h.trackingService.Changes(r.Context(), trackCode, since)
+	if errors.Is(err, service.ErrChangesDisabled) {
+		h.writeErrorResponse(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("handler.GetTrackChanges.Error: %s: %v", trackCode, err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "failed to load tracking changes")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":     true,
+		"track_code": trackCode,
+		"changes":    changes,
+	})
+}
+
+func (h *TrackHandler) WatchTrack(w http.ResponseWriter, r *http.Request) {
+	trackCode := strings.TrimSpace(mux.Vars(r)["trackCode"])
+	if trackCode == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "track_code is required")
+		return
+	}
+
+	watch, err := h.trackingService.Watch(r.Context(), trackCode)
+	if err != nil {
+		h.writeWatchError(w, trackCode, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status": true,
+		"watch":  watch,
+	})
+}
+
+func (h *TrackHandler) GetWatch(w http.ResponseWriter, r *http.Request) {
+	trackCode := strings.TrimSpace(mux.Vars(r)["trackCode"])
+
+	watch, err := h.trackingService.GetWatch(r.Context(), trackCode)
+	if err != nil {
+		h.writeWatchError(w, trackCode, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status": true,
+		"watch":  watch,
+	})
+}
+
+func (h *TrackHandler) UnwatchTrack(w http.ResponseWriter, r *http.Request) {
+	trackCode := strings.TrimSpace(mux.Vars(r)["trackCode"])
+
+	if err := h.trackingService.Unwatch(r.Context(), trackCode); err != nil {
+		h.writeWatchError(w, trackCode, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status": true,
+	})
+}
+
+func (h *TrackHandler) writeWatchError(w http.ResponseWriter, trackCode string, err error) {
+	switch {
+	case errors.Is(err, service.ErrSchedulerDisabled):
+		h.writeErrorResponse(w, http.StatusNotImplemented, err.Error())
+	case errors.Is(err, erors.ErrInvalidTrackCode):
+		h.writeErrorResponse(w, http.StatusBadRequest, erors.ErrInvalidTrackCode.Error())
+	case errors.Is(err, repository.ErrWatchNotFound):
+		h.writeErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("handler.Watch.Error: %s: %v", trackCode, err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "failed to update watch")
+	}
+}
+
+// oldestFirst returns a copy of data with events reversed; the original may be
+// shared with the cache.
+func oldestFirst(data *models.TrackData) *models.TrackData {
+	if data == nil {
+		return nil
+	}
+
+	reversed := *data
+	reversed.Events = make([]models.Event, len(data.Events))
+	for i, event := range data.Events {
+		reversed.Events[len(data.Events)-1-i] = event
+	}
+	return &reversed
+}
+
+// retryAfterSeconds formats d for the Retry-After header, which only takes
+// whole seconds.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
 func (h *TrackHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.writeErrorResponse(w, http.StatusMethodNotAllowed, "only GET method is supported")
@@ -62,10 +240,18 @@ func (h *TrackHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	details := h.trackingService.HealthDetails(r.Context())
+
+	message := "service is healthy"
+	if len(details.Warnings) > 0 {
+		message = "service is degraded"
+	}
+
 	response := map[string]interface{}{
 		"status":  true,
-		"message": "service is healthy",
+		"message": message,
 		"service": "proxy_track_service",
+		"details": details,
 	}
 
 	h.writeJSONResponse(w, http.StatusOK, response)
@@ -79,10 +265,18 @@ func (h *TrackHandler) getStatusCodeFromError(errorMsg string) int {
 		return http.StatusBadRequest
 	case strings.Contains(errorMsg, "tracking service temporarily unavailable"):
 		return http.StatusServiceUnavailable
+	case strings.Contains(errorMsg, "tracking provider blocked the request"):
+		return http.StatusServiceUnavailable
 	case strings.Contains(errorMsg, "request timeout"):
 		return http.StatusRequestTimeout
 	case strings.Contains(errorMsg, "too many requests"):
 		return http.StatusTooManyRequests
+	case strings.Contains(errorMsg, "service busy"):
+		return http.StatusTooManyRequests
+	case strings.Contains(errorMsg, "service shutting down"):
+		return http.StatusServiceUnavailable
+	case strings.Contains(errorMsg, "service is not running"):
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Process-wide counters, published through expvar and served as JSON on /metrics.
+var (
+	ScraperBlockedRequests = expvar.NewMap("scraper_blocked_requests")
+	ScraperBytesSaved      = expvar.NewInt("scraper_bytes_saved_estimated")
+	ProviderBlocked        = expvar.NewMap("provider_blocked")
+	LayoutEmptyParses      = expvar.NewInt("layout_empty_parses")
+	LayoutDriftAlarm       = expvar.NewInt("layout_drift_alarm")
+	CountryUnmapped        = expvar.NewInt("country_unmapped")
+	SchedulerRefreshes     = expvar.NewInt("scheduler_refreshes")
+	SchedulerErrors        = expvar.NewInt("scheduler_errors")
+	SchedulerStopped       = expvar.NewMap("scheduler_stopped")
+	BatcherRejected        = expvar.NewMap("batcher_rejected")
+	BatcherOverflow        = expvar.NewMap("batcher_overflow")
+	BatcherCancelled       = expvar.NewMap("batcher_cancelled")
+	BatcherPersisted       = expvar.NewInt("batcher_persisted")
+	BatcherRecovered       = expvar.NewInt("batcher_recovered")
+	BatcherBatchSize       = expvar.NewInt("batcher_batch_size")
+	BatcherFlushTimeout    = expvar.NewInt("batcher_flush_timeout_ms")
+	BatcherScrapeLatency   = expvar.NewInt("batcher_scrape_latency_ms")
+	BatcherErrorRate       = expvar.NewFloat("batcher_error_rate")
+	RateLimitWaits         = expvar.NewMap("ratelimit_waits")
+	RateLimitWaitMs        = expvar.NewMap("ratelimit_wait_ms")
+	RateLimitErrors        = expvar.NewMap("ratelimit_errors")
+)
+
+func Handler() http.Handler {
+	return expvar.Handler()
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 type TrackRequest struct {
 	TrackCode string `json:"track_code"`
 }
@@ -12,16 +17,45 @@ type TrackResponse struct {
 	Status bool       `json:"status"`
 	Data   *TrackData `json:"data,omitempty"`
 	Error  string     `json:"error,omitempty"`
+
+	// RetryAfter tells a rejected caller when to try again; it is sent as
+	// the Retry-After header.
+	RetryAfter time.Duration `json:"-"`
 }
 
 type TrackData struct {
-	Countries []string `json:"countries"`
-	Events    []Event  `json:"events"`
+	// Countries holds the origin and destination alpha-2 codes, kept for
+	// existing clients; unmapped names are passed through as is.
+	Countries   []string `json:"countries"`
+	Origin      *Country `json:"origin"`
+	Destination *Country `json:"destination"`
+	// Events are unique and ordered newest first; FirstEvent and LastEvent
+	// are the oldest and the newest of them.
+	Events     []Event `json:"events"`
+	FirstEvent *Event  `json:"first_event,omitempty"`
+	LastEvent  *Event  `json:"last_event,omitempty"`
+}
+
+// Country is an ISO 3166-1 country. Code is empty when the provider's name
+// could not be mapped, in which case Name is the raw text.
+type Country struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
 }
 
 type Event struct {
 	Status string `json:"status"`
-	Date   string `json:"date"`
+	// Location, Facility and Description are split out of Status; Code is one
+	// of the Event* constants.
+	Location    string `json:"location,omitempty"`
+	Facility    string `json:"facility,omitempty"`
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	// Date is RFC 3339 with the offset of the place the event happened.
+	Date string `json:"date"`
+	// DateUTC and DateLocal are only filled when dual times are enabled.
+	DateUTC   string `json:"date_utc,omitempty"`
+	DateLocal string `json:"date_local,omitempty"`
 }
 
 const (
@@ -33,3 +67,80 @@ const (
 	StatusReturned  = "Returned"
 	StatusUnknown   = "Unknown"
 )
+
+// Key identifies an event by its instant and normalized status text, so the
+// same event reported in different offsets or spacing compares equal.
+func (e Event) Key() string {
+	instant := e.Date
+	if t, err := time.Parse(time.RFC3339, e.Date); err == nil {
+		instant = t.UTC().Format(time.RFC3339)
+	}
+	return instant + "|" + strings.Join(strings.Fields(strings.ToLower(e.Status)), " ")
+}
+
+// HistoryEvent is an event from the persistent history with the time the
+// service first saw it.
+type HistoryEvent struct {
+	Event
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// TrackChange is what a scrape added compared to the previous one.
+type TrackChange struct {
+	DetectedAt  time.Time `json:"detected_at"`
+	AddedEvents []Event   `json:"added_events"`
+	// Transition is set when the code of the latest event changed.
+	Transition *StatusTransition `json:"transition,omitempty"`
+}
+
+type StatusTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Watch is a track code the scheduler refreshes in the background.
+type Watch struct {
+	TrackCode   string     `json:"track_code"`
+	AddedAt     time.Time  `json:"added_at"`
+	NextCheck   time.Time  `json:"next_check"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastCode    string     `json:"last_code,omitempty"`
+	Checks      int        `json:"checks"`
+}
+
+// PendingRequest is a track code that was still queued when the service shut
+// down, kept to be fetched after the next start.
+type PendingRequest struct {
+	TrackCode string    `json:"track_code"`
+	Priority  string    `json:"priority"`
+	QueuedAt  time.Time `json:"queued_at"`
+}
+
+// Normalized event codes shared by all providers.
+const (
+	EventInfoReceived   = "INFO_RECEIVED"
+	EventAccepted       = "ACCEPTED"
+	EventDeparted       = "DEPARTED"
+	EventArrived        = "ARRIVED"
+	EventInTransit      = "IN_TRANSIT"
+	EventCustoms        = "CUSTOMS"
+	EventCustomsCleared = "CUSTOMS_CLEARED"
+	EventOutForDelivery = "OUT_FOR_DELIVERY"
+	EventDeliveryFailed = "DELIVERY_FAILED"
+	EventDelivered      = "DELIVERED"
+	EventReturned       = "RETURNED"
+	EventException      = "EXCEPTION"
+	EventUnknown        = "UNKNOWN"
+)
+
+// Snapshot describes a stored copy of a scraped page kept for debugging.
+type Snapshot struct {
+	ID            string    `json:"id"`
+	BatchID       string    `json:"batch_id"`
+	TrackCodes    []string  `json:"track_codes"`
+	Reason        string    `json:"reason"`
+	URL           string    `json:"url"`
+	CreatedAt     time.Time `json:"created_at"`
+	HTMLSize      int       `json:"html_size"`
+	HasScreenshot bool      `json:"has_screenshot"`
+}
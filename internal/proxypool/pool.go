@@ -0,0 +1,232 @@
+package proxypool
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+)
+
+// Proxy is a single outbound proxy of the pool. Health fields are guarded by
+// the owning pool.
+type Proxy struct {
+	server   string
+	Username string
+	Password string
+
+	successes           int64
+	failures            int64
+	consecutiveFailures int
+	avgLatency          time.Duration
+	ejectedUntil        time.Time
+	lastError           string
+	lastUsed            time.Time
+}
+
+// Server returns the proxy address without credentials, in the form Chrome
+// accepts for --proxy-server.
+func (p *Proxy) Server() string {
+	return p.server
+}
+
+func (p *Proxy) HasCredentials() bool {
+	return p.Username != ""
+}
+
+type ProxyState struct {
+	Server       string        `json:"server"`
+	Healthy      bool          `json:"healthy"`
+	Score        float64       `json:"score"`
+	Successes    int64         `json:"successes"`
+	Failures     int64         `json:"failures"`
+	AvgLatency   time.Duration `json:"avg_latency"`
+	EjectedUntil *time.Time    `json:"ejected_until,omitempty"`
+	LastError    string        `json:"last_error,omitempty"`
+	LastUsed     *time.Time    `json:"last_used,omitempty"`
+}
+
+type Pool struct {
+	config config.ProxyConfig
+
+	mu      sync.Mutex
+	proxies []*Proxy
+	next    int
+}
+
+func NewPool(cfg config.ProxyConfig) (*Pool, error) {
+	pool := &Pool{config: cfg}
+
+	for _, raw := range cfg.URLs {
+		proxy, err := parseProxy(raw)
+		if err != nil {
+			return nil, err
+		}
+		pool.proxies = append(pool.proxies, proxy)
+	}
+
+	return pool, nil
+}
+
+func parseProxy(raw string) (*Proxy, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q in %s", u.Scheme, u.Host)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+
+	proxy := &Proxy{server: fmt.Sprintf("%s://%s", u.Scheme, u.Host)}
+	if u.User != nil {
+		// Chrome cannot authenticate to SOCKS5 proxies; sending traffic
+		// through one without the configured credentials is never intended.
+		if u.Scheme == "socks5" {
+			return nil, fmt.Errorf("SOCKS5 proxy %s has credentials, which Chrome does not support", proxy.server)
+		}
+		proxy.Username = u.User.Username()
+		proxy.Password, _ = u.User.Password()
+	}
+
+	return proxy, nil
+}
+
+func (p *Pool) Size() int {
+	return len(p.proxies)
+}
+
+// Next rotates to the next proxy that is not ejected. When every proxy is
+// ejected the one that comes back soonest is returned, so traffic never falls
+// back to the service's own IP. Returns nil for an empty pool.
+func (p *Pool) Next() *Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.proxies) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := 0; i < len(p.proxies); i++ {
+		proxy := p.proxies[(p.next+i)%len(p.proxies)]
+		if now.After(proxy.ejectedUntil) {
+			p.next = (p.next + i + 1) % len(p.proxies)
+			proxy.lastUsed = now
+			return proxy
+		}
+	}
+
+	soonest := p.proxies[0]
+	for _, proxy := range p.proxies[1:] {
+		if proxy.ejectedUntil.Before(soonest.ejectedUntil) {
+			soonest = proxy
+		}
+	}
+	soonest.lastUsed = now
+	return soonest
+}
+
+// Report records the outcome of a scrape made through the proxy. Failures and
+// responses slower than the configured threshold count against the proxy; after
+// MaxFailures in a row it is ejected for EjectDuration.
+func (p *Pool) Report(proxy *Proxy, latency time.Duration, err error) {
+	if proxy == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if proxy.avgLatency == 0 {
+		proxy.avgLatency = latency
+	} else {
+		proxy.avgLatency = (proxy.avgLatency*4 + latency) / 5
+	}
+
+	if err == nil && (p.config.SlowThreshold <= 0 || latency <= p.config.SlowThreshold) {
+		proxy.successes++
+		proxy.consecutiveFailures = 0
+		return
+	}
+
+	proxy.failures++
+	proxy.consecutiveFailures++
+	if err != nil {
+		proxy.lastError = err.Error()
+	} else {
+		proxy.lastError = fmt.Sprintf("slow response: %v", latency)
+	}
+
+	if proxy.consecutiveFailures >= p.config.MaxFailures {
+		proxy.ejectedUntil = time.Now().Add(p.config.EjectDuration)
+		proxy.consecutiveFailures = 0
+		log.Printf("proxypool.Report.Ejected: %s until %s: %s", proxy.server, proxy.ejectedUntil.Format(time.RFC3339), proxy.lastError)
+	}
+}
+
+// Snapshot returns the state of every proxy, best score first.
+func (p *Pool) Snapshot() []ProxyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	states := make([]ProxyState, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		state := ProxyState{
+			Server:     proxy.server,
+			Healthy:    now.After(proxy.ejectedUntil),
+			Score:      p.score(proxy),
+			Successes:  proxy.successes,
+			Failures:   proxy.failures,
+			AvgLatency: proxy.avgLatency,
+			LastError:  proxy.lastError,
+		}
+		if !state.Healthy {
+			until := proxy.ejectedUntil
+			state.EjectedUntil = &until
+		}
+		if !proxy.lastUsed.IsZero() {
+			lastUsed := proxy.lastUsed
+			state.LastUsed = &lastUsed
+		}
+		states = append(states, state)
+	}
+
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Score > states[j].Score
+	})
+
+	return states
+}
+
+// score is the success ratio scaled to 0..100, reduced by the share of the slow
+// threshold the average latency takes up.
+func (p *Pool) score(proxy *Proxy) float64 {
+	total := proxy.successes + proxy.failures
+	if total == 0 {
+		return 100
+	}
+
+	score := 100 * float64(proxy.successes) / float64(total)
+	if p.config.SlowThreshold > 0 {
+		penalty := 50 * float64(proxy.avgLatency) / float64(p.config.SlowThreshold)
+		if penalty > 50 {
+			penalty = 50
+		}
+		score -= penalty
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	return score
+}
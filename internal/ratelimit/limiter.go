@@ -0,0 +1,173 @@
+// Package ratelimit caps how hard all replicas together hit a provider. Each
+// page waits for a free session slot and a token of the provider's bucket,
+// both kept in Redis.
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+const (
+	// sessionPoll is how often a page waiting for a session slot asks again.
+	sessionPoll = 250 * time.Millisecond
+	// releaseTimeout bounds freeing a session slot.
+	releaseTimeout = 5 * time.Second
+)
+
+type Limiter struct {
+	config config.RateLimitConfig
+	store  repository.RateLimitRepository
+	holder string
+	seq    atomic.Int64
+}
+
+func New(cfg config.RateLimitConfig, store repository.RateLimitRepository) *Limiter {
+	return &Limiter{
+		config: cfg,
+		store:  store,
+		holder: newHolderID(),
+	}
+}
+
+// Gate returns a client.PageGate that limits pages of provider.
+func (l *Limiter) Gate(provider string) client.PageGate {
+	return func(ctx context.Context) (func(), error) {
+		return l.Acquire(ctx, provider)
+	}
+}
+
+// Acquire waits until provider has a free session slot and a page token and
+// returns the function that frees the slot. It fails only when ctx is done;
+// Redis errors let the page through, so an outage of Redis does not stop
+// scraping.
+func (l *Limiter) Acquire(ctx context.Context, provider string) (func(), error) {
+	limit := l.config.Limit(provider)
+
+	release, err := l.acquireSession(ctx, provider, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.takeToken(ctx, provider, limit); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (l *Limiter) acquireSession(ctx context.Context, provider string, limit config.ProviderLimit) (func(), error) {
+	if limit.MaxSessions <= 0 {
+		return func() {}, nil
+	}
+
+	holder := fmt.Sprintf("%s-%d", l.holder, l.seq.Add(1))
+	wait := newWaitTimer(provider, "sessions")
+	defer wait.observe()
+
+	for {
+		acquired, err := l.store.AcquireSession(ctx, provider, holder, limit.MaxSessions, l.config.SessionTTL)
+		if err != nil {
+			l.failOpen(provider, err)
+			return func() {}, nil
+		}
+		if acquired {
+			break
+		}
+		if err := wait.sleep(ctx, sessionPoll); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+
+		if err := l.store.ReleaseSession(ctx, provider, holder); err != nil {
+			log.Printf("ratelimit.Release.Error: %s: %v", provider, err)
+		}
+	}, nil
+}
+
+func (l *Limiter) takeToken(ctx context.Context, provider string, limit config.ProviderLimit) error {
+	if limit.PagesPerMinute <= 0 {
+		return nil
+	}
+
+	rate := limit.PagesPerMinute / 60
+	burst := max(limit.Burst, 1)
+	wait := newWaitTimer(provider, "tokens")
+	defer wait.observe()
+
+	for {
+		delay, err := l.store.TakeToken(ctx, provider, rate, burst)
+		if err != nil {
+			l.failOpen(provider, err)
+			return nil
+		}
+		if delay <= 0 {
+			return nil
+		}
+		if err := wait.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *Limiter) failOpen(provider string, err error) {
+	log.Printf("ratelimit.Acquire.StoreError: %s, letting the page through: %v", provider, err)
+	metrics.RateLimitErrors.Add(provider, 1)
+}
+
+// waitTimer measures how long a page waited for one limit.
+type waitTimer struct {
+	key    string
+	start  time.Time
+	waited bool
+}
+
+func newWaitTimer(provider, limit string) *waitTimer {
+	return &waitTimer{key: provider + "_" + limit, start: time.Now()}
+}
+
+func (w *waitTimer) sleep(ctx context.Context, d time.Duration) error {
+	w.waited = true
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// observe counts the wait, by provider and limit, if the page had to wait.
+func (w *waitTimer) observe() {
+	if !w.waited {
+		return
+	}
+	metrics.RateLimitWaits.Add(w.key, 1)
+	metrics.RateLimitWaitMs.Add(w.key, time.Since(w.start).Milliseconds())
+}
+
+func newHolderID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+)
+
+// memoryStore is an in-process RateLimitRepository. Its bucket hands out
+// burst tokens and then asks every caller to wait refill.
+type memoryStore struct {
+	mu       sync.Mutex
+	tokens   map[string]int
+	sessions map[string]map[string]bool
+	refill   time.Duration
+	err      error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		tokens:   make(map[string]int),
+		sessions: make(map[string]map[string]bool),
+		refill:   20 * time.Millisecond,
+	}
+}
+
+func (s *memoryStore) TakeToken(ctx context.Context, provider string, rate float64, burst int) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	if s.tokens[provider] >= burst {
+		s.tokens[provider] = 0
+		return s.refill, nil
+	}
+	s.tokens[provider]++
+	return 0, nil
+}
+
+func (s *memoryStore) AcquireSession(ctx context.Context, provider, holder string, limit int, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+
+	if s.sessions[provider] == nil {
+		s.sessions[provider] = make(map[string]bool)
+	}
+	if len(s.sessions[provider]) >= limit {
+		return false, nil
+	}
+	s.sessions[provider][holder] = true
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseSession(ctx context.Context, provider, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions[provider], holder)
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+func newTestLimiter(store *memoryStore, limit config.ProviderLimit) *Limiter {
+	return New(config.RateLimitConfig{
+		SessionTTL: time.Minute,
+		Providers:  map[string]config.ProviderLimit{"test": limit},
+	}, store)
+}
+
+// TestLimiterSessions - страница ждет свободный слот сессии
+func TestLimiterSessions(t *testing.T) {
+	store := newMemoryStore()
+	limiter := newTestLimiter(store, config.ProviderLimit{MaxSessions: 1})
+
+	release, err := limiter.Acquire(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := limiter.Acquire(context.Background(), "test")
+		if err == nil {
+			second()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second session started while the only slot was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second session did not start after the slot was freed")
+	}
+}
+
+// TestLimiterTokens - пустой бакет задерживает страницу, ожидание попадает в метрики
+func TestLimiterTokens(t *testing.T) {
+	store := newMemoryStore()
+	limiter := newTestLimiter(store, config.ProviderLimit{PagesPerMinute: 60, Burst: 1})
+	waitsBefore := metricValue("test_tokens")
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		release, err := limiter.Acquire(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < store.refill {
+		t.Errorf("two pages took %s, want at least the refill %s", elapsed, store.refill)
+	}
+	if waits := metricValue("test_tokens"); waits != waitsBefore+1 {
+		t.Errorf("ratelimit_waits = %d, want %d", waits, waitsBefore+1)
+	}
+}
+
+// TestLimiterCancelled - ожидание прерывается отменой контекста
+func TestLimiterCancelled(t *testing.T) {
+	store := newMemoryStore()
+	limiter := newTestLimiter(store, config.ProviderLimit{MaxSessions: 1})
+
+	if _, err := limiter.Acquire(context.Background(), "test"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "test"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire with a held slot = %v, want deadline exceeded", err)
+	}
+}
+
+// TestLimiterFailsOpen - ошибки Redis не останавливают скрапинг
+func TestLimiterFailsOpen(t *testing.T) {
+	store := newMemoryStore()
+	store.err = errors.New("connection refused")
+	limiter := newTestLimiter(store, config.ProviderLimit{PagesPerMinute: 1, Burst: 1, MaxSessions: 1})
+
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+		release()
+	}
+}
+
+func metricValue(key string) int64 {
+	if value, ok := metrics.RateLimitWaits.Get(key).(*expvar.Int); ok {
+		return value.Value()
+	}
+	return 0
+}
@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrTrackDataNotFound = errors.New("track data not found")
+	ErrSnapshotNotFound  = errors.New("snapshot not found")
+	ErrWatchNotFound     = errors.New("watch not found")
 )
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+const (
+	snapshotMetaFile       = "meta.json"
+	snapshotHTMLFile       = "page.html"
+	snapshotScreenshotFile = "screenshot.png"
+)
+
+// FileSnapshotStore keeps every snapshot in its own directory under dir and
+// prunes the oldest ones beyond maxCount or older than retention on each save.
+type FileSnapshotStore struct {
+	dir       string
+	maxCount  int
+	retention time.Duration
+
+	mu sync.Mutex
+}
+
+func NewFileSnapshotStore(dir string, maxCount int, retention time.Duration) (SnapshotRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	return &FileSnapshotStore{
+		dir:       dir,
+		maxCount:  maxCount,
+		retention: retention,
+	}, nil
+}
+
+func (s *FileSnapshotStore) Save(ctx context.Context, snapshot *models.Snapshot, html string, screenshot []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshotDir, err := s.snapshotDir(snapshot.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(snapshotDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	meta, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(snapshotDir, snapshotHTMLFile), []byte(html), 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot html: %w", err)
+	}
+	if len(screenshot) > 0 {
+		if err := os.WriteFile(filepath.Join(snapshotDir, snapshotScreenshotFile), screenshot, 0o644); err != nil {
+			return fmt.Errorf("failed to write snapshot screenshot: %w", err)
+		}
+	}
+	// meta.json is written last, a directory without it is ignored by List.
+	if err := os.WriteFile(filepath.Join(snapshotDir, snapshotMetaFile), meta, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot meta: %w", err)
+	}
+
+	s.pruneLocked()
+	return nil
+}
+
+func (s *FileSnapshotStore) List(ctx context.Context, batchID string) ([]models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.listLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]models.Snapshot, 0, len(all))
+	for _, snapshot := range all {
+		if batchID == "" || snapshot.BatchID == batchID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (s *FileSnapshotStore) GetHTML(ctx context.Context, id string) (string, error) {
+	content, err := s.readFile(id, snapshotHTMLFile)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func (s *FileSnapshotStore) GetScreenshot(ctx context.Context, id string) ([]byte, error) {
+	return s.readFile(id, snapshotScreenshotFile)
+}
+
+func (s *FileSnapshotStore) readFile(id, name string) ([]byte, error) {
+	snapshotDir, err := s.snapshotDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(snapshotDir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return content, nil
+}
+
+// snapshotDir resolves the directory of a snapshot and rejects IDs that would
+// escape the store directory.
+func (s *FileSnapshotStore) snapshotDir(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return "", ErrSnapshotNotFound
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+// listLocked returns all complete snapshots, newest first.
+func (s *FileSnapshotStore) listLocked() ([]models.Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	snapshots := make([]models.Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		meta, err := os.ReadFile(filepath.Join(s.dir, entry.Name(), snapshotMetaFile))
+		if err != nil {
+			continue
+		}
+
+		var snapshot models.Snapshot
+		if err := json.Unmarshal(meta, &snapshot); err != nil {
+			continue
+		}
+		// The directory name is what GetHTML and pruning resolve, whatever
+		// ID meta.json claims.
+		snapshot.ID = entry.Name()
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+func (s *FileSnapshotStore) pruneLocked() {
+	snapshots, err := s.listLocked()
+	if err != nil {
+		log.Printf("repository.FileSnapshotStore.PruneError: %v", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-s.retention)
+	for i, snapshot := range snapshots {
+		expired := s.retention > 0 && snapshot.CreatedAt.Before(cutoff)
+		overflow := s.maxCount > 0 && i >= s.maxCount
+		if !expired && !overflow {
+			continue
+		}
+
+		snapshotDir, err := s.snapshotDir(snapshot.ID)
+		if err != nil {
+			continue
+		}
+		if err := os.RemoveAll(snapshotDir); err != nil {
+			log.Printf("repository.FileSnapshotStore.PruneError: %v", err)
+		}
+	}
+}
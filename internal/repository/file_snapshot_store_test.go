@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+func newTestSnapshotStore(t *testing.T, maxCount int, retention time.Duration) (*FileSnapshotStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewFileSnapshotStore(dir, maxCount, retention)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store.(*FileSnapshotStore), dir
+}
+
+func saveSnapshot(t *testing.T, store *FileSnapshotStore, id, batchID string, createdAt time.Time) {
+	t.Helper()
+	snapshot := &models.Snapshot{ID: id, BatchID: batchID, CreatedAt: createdAt}
+	if err := store.Save(context.Background(), snapshot, "<html>"+id+"</html>", []byte("png")); err != nil {
+		t.Fatalf("Save(%s) failed: %v", id, err)
+	}
+}
+
+func snapshotIDs(snapshots []models.Snapshot) []string {
+	ids := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		ids = append(ids, snapshot.ID)
+	}
+	return ids
+}
+
+// TestFileSnapshotStoreTraversal - ID снимка не может выйти за пределы каталога хранилища
+func TestFileSnapshotStoreTraversal(t *testing.T) {
+	store, _ := newTestSnapshotStore(t, 0, 0)
+	ctx := context.Background()
+
+	for _, id := range []string{"", ".", "..", "a/b", "../outside", "/etc"} {
+		if _, err := store.GetHTML(ctx, id); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Errorf("GetHTML(%q) err = %v, want not found", id, err)
+		}
+		if _, err := store.GetScreenshot(ctx, id); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Errorf("GetScreenshot(%q) err = %v, want not found", id, err)
+		}
+		if err := store.Save(ctx, &models.Snapshot{ID: id}, "<html></html>", nil); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Errorf("Save(%q) err = %v, want it refused", id, err)
+		}
+	}
+}
+
+// TestFileSnapshotStoreList - снимки отдаются от новых к старым с фильтром по батчу
+func TestFileSnapshotStoreList(t *testing.T) {
+	store, _ := newTestSnapshotStore(t, 0, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	saveSnapshot(t, store, "snap-1", "batch-a", now.Add(-3*time.Minute))
+	saveSnapshot(t, store, "snap-2", "batch-b", now.Add(-2*time.Minute))
+	saveSnapshot(t, store, "snap-3", "batch-a", now.Add(-time.Minute))
+
+	all, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if got := snapshotIDs(all); len(got) != 3 || got[0] != "snap-3" || got[2] != "snap-1" {
+		t.Errorf("List() = %v, want newest first", got)
+	}
+
+	filtered, err := store.List(ctx, "batch-a")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if got := snapshotIDs(filtered); len(got) != 2 || got[0] != "snap-3" || got[1] != "snap-1" {
+		t.Errorf("List(batch-a) = %v, want [snap-3 snap-1]", got)
+	}
+
+	html, err := store.GetHTML(ctx, "snap-2")
+	if err != nil || html != "<html>snap-2</html>" {
+		t.Errorf("GetHTML = %q, %v", html, err)
+	}
+	if _, err := store.GetHTML(ctx, "snap-9"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("GetHTML(missing) err = %v, want not found", err)
+	}
+}
+
+// TestFileSnapshotStorePrune - удаление сверх maxCount и старше retention
+func TestFileSnapshotStorePrune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("by count", func(t *testing.T) {
+		store, _ := newTestSnapshotStore(t, 2, 0)
+		saveSnapshot(t, store, "old", "b", now.Add(-3*time.Minute))
+		saveSnapshot(t, store, "mid", "b", now.Add(-2*time.Minute))
+		saveSnapshot(t, store, "new", "b", now.Add(-time.Minute))
+
+		snapshots, _ := store.List(ctx, "")
+		if got := snapshotIDs(snapshots); len(got) != 2 || got[0] != "new" || got[1] != "mid" {
+			t.Errorf("kept %v, want [new mid]", got)
+		}
+	})
+
+	t.Run("by retention", func(t *testing.T) {
+		store, _ := newTestSnapshotStore(t, 0, time.Hour)
+		saveSnapshot(t, store, "expired", "b", now.Add(-2*time.Hour))
+		saveSnapshot(t, store, "fresh", "b", now)
+
+		snapshots, _ := store.List(ctx, "")
+		if got := snapshotIDs(snapshots); len(got) != 1 || got[0] != "fresh" {
+			t.Errorf("kept %v, want [fresh]", got)
+		}
+	})
+
+	t.Run("meta id ignored", func(t *testing.T) {
+		store, dir := newTestSnapshotStore(t, 0, time.Hour)
+		outside := filepath.Join(filepath.Dir(dir), "victim")
+		if err := os.Mkdir(outside, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		// A snapshot whose meta.json points outside the store.
+		saveSnapshot(t, store, "tampered", "b", now)
+		meta := `{"id":"../victim","created_at":"` + now.Add(-2*time.Hour).Format(time.RFC3339) + `"}`
+		if err := os.WriteFile(filepath.Join(dir, "tampered", snapshotMetaFile), []byte(meta), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		saveSnapshot(t, store, "fresh", "b", now)
+
+		if _, err := os.Stat(outside); err != nil {
+			t.Errorf("pruning removed a directory outside the store: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "tampered")); !os.IsNotExist(err) {
+			t.Errorf("expired snapshot still on disk, err = %v", err)
+		}
+	})
+}
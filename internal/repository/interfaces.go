@@ -17,3 +17,73 @@ type CacheRepository interface {
 	Health(ctx context.Context) error
 	Close() error
 }
+
+type SnapshotRepository interface {
+	Save(ctx context.Context, snapshot *models.Snapshot, html string, screenshot []byte) error
+	List(ctx context.Context, batchID string) ([]models.Snapshot, error)
+	GetHTML(ctx context.Context, id string) (string, error)
+	GetScreenshot(ctx context.Context, id string) ([]byte, error)
+}
+
+// HistoryRepository keeps every distinct event ever seen per track code,
+// independent of the cache TTL.
+type HistoryRepository interface {
+	// AddEvents stores events not seen before and returns how many were new.
+	AddEvents(ctx context.Context, trackCode string, events []models.Event, seenAt time.Time) (int, error)
+	GetHistory(ctx context.Context, trackCode string) ([]models.HistoryEvent, error)
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// ChangeRepository keeps the last scraped state of every track code and the
+// changes detected between scrapes.
+type ChangeRepository interface {
+	// SwapLatest stores data as the latest state and returns the previous one,
+	// or nil on the first scrape.
+	SwapLatest(ctx context.Context, trackCode string, data *models.TrackData) (*models.TrackData, error)
+	AddChange(ctx context.Context, trackCode string, change *models.TrackChange) error
+	// ListChanges returns changes detected after since, oldest first.
+	ListChanges(ctx context.Context, trackCode string, since time.Time) ([]models.TrackChange, error)
+	Close() error
+}
+
+// WatchRepository keeps watched track codes ordered by their next check and
+// the leases that stop replicas from refreshing the same code twice.
+type WatchRepository interface {
+	// Add stores watch unless the code is already watched and returns the
+	// stored watch.
+	Add(ctx context.Context, watch *models.Watch) (*models.Watch, error)
+	Get(ctx context.Context, trackCode string) (*models.Watch, error)
+	// Save updates watch and moves it to watch.NextCheck.
+	Save(ctx context.Context, watch *models.Watch) error
+	Remove(ctx context.Context, trackCode string) error
+	// Due returns up to limit codes whose next check is not after now.
+	Due(ctx context.Context, now time.Time, limit int) ([]string, error)
+	Count(ctx context.Context) (int64, error)
+	AcquireLease(ctx context.Context, trackCode, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, trackCode, owner string) error
+	Close() error
+}
+
+// PendingQueueRepository keeps requests left over at shutdown until the next
+// start picks them up.
+type PendingQueueRepository interface {
+	Push(ctx context.Context, requests []models.PendingRequest) error
+	// PopAll removes and returns every stored request, oldest first.
+	PopAll(ctx context.Context) ([]models.PendingRequest, error)
+	Close() error
+}
+
+// RateLimitRepository keeps the provider limits shared by every replica: a
+// token bucket of pages and a pool of session slots per provider.
+type RateLimitRepository interface {
+	// TakeToken takes a token from the bucket of provider, refilled at rate
+	// tokens per second up to burst. When the bucket is empty it takes
+	// nothing and returns how long until a token is available.
+	TakeToken(ctx context.Context, provider string, rate float64, burst int) (time.Duration, error)
+	// AcquireSession takes one of limit session slots of provider for holder
+	// until ttl passes or the slot is released.
+	AcquireSession(ctx context.Context, provider, holder string, limit int, ttl time.Duration) (bool, error)
+	ReleaseSession(ctx context.Context, provider, holder string) error
+	Close() error
+}
@@ -17,6 +17,18 @@ type RedisCache struct {
 }
 
 func NewRedisCache(cfg config.RedisConfig) (CacheRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisCache{
+		client: rdb,
+		config: cfg,
+	}, nil
+}
+
+func newRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 		Password: cfg.Password,
@@ -27,13 +39,11 @@ func NewRedisCache(cfg config.RedisConfig) (CacheRepository, error) {
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisCache{
-		client: rdb,
-		config: cfg,
-	}, nil
+	return rdb, nil
 }
 
 func (r *RedisCache) Get(ctx context.Context, key string) (interface{}, error) {
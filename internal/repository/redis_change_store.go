@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// RedisChangeStore keeps the latest state under track:{code}:latest and the
+// changes in a sorted set track:{code}:changes scored by detection time in
+// milliseconds, which a float64 score holds exactly. Both live for retention
+// after the last scrape; at most maxPerCode changes are kept.
+type RedisChangeStore struct {
+	client     *redis.Client
+	retention  time.Duration
+	maxPerCode int
+}
+
+func NewRedisChangeStore(cfg config.RedisConfig, retention time.Duration, maxPerCode int) (ChangeRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisChangeStore{
+		client:     rdb,
+		retention:  retention,
+		maxPerCode: maxPerCode,
+	}, nil
+}
+
+func latestKey(trackCode string) string {
+	return fmt.Sprintf("track:%s:latest", trackCode)
+}
+
+func changesKey(trackCode string) string {
+	return fmt.Sprintf("track:%s:changes", trackCode)
+}
+
+func (s *RedisChangeStore) SwapLatest(ctx context.Context, trackCode string, data *models.TrackData) (*models.TrackData, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal track data: %w", err)
+	}
+
+	previous, err := s.client.SetArgs(ctx, latestKey(trackCode), jsonData, redis.SetArgs{
+		TTL: s.retention,
+		Get: true,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to swap latest track data: %w", err)
+	}
+
+	var trackData models.TrackData
+	if err := json.Unmarshal([]byte(previous), &trackData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal track data: %w", err)
+	}
+
+	return &trackData, nil
+}
+
+func (s *RedisChangeStore) AddChange(ctx context.Context, trackCode string, change *models.TrackChange) error {
+	member, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change: %w", err)
+	}
+
+	key := changesKey(trackCode)
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(change.DetectedAt.UnixMilli()),
+		Member: member,
+	})
+	if s.maxPerCode > 0 {
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-s.maxPerCode-1))
+	}
+	if s.retention > 0 {
+		pipe.Expire(ctx, key, s.retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save change: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisChangeStore) ListChanges(ctx context.Context, trackCode string, since time.Time) ([]models.TrackChange, error) {
+	// Scores are whole milliseconds, so the range starts at the millisecond of
+	// since and changes at or before since are dropped after decoding.
+	min := "-inf"
+	if !since.IsZero() {
+		min = fmt.Sprintf("%d", since.UnixMilli())
+	}
+
+	members, err := s.client.ZRangeByScore(ctx, changesKey(trackCode), &redis.ZRangeBy{
+		Min: min,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	changes := make([]models.TrackChange, 0, len(members))
+	for _, member := range members {
+		var change models.TrackChange
+		if err := json.Unmarshal([]byte(member), &change); err != nil {
+			continue
+		}
+		if !since.IsZero() && !change.DetectedAt.After(since) {
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func (s *RedisChangeStore) Close() error {
+	return s.client.Close()
+}
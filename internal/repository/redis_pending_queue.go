@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+const pendingQueueKey = "batcher:pending"
+
+// RedisPendingQueue keeps pending requests as JSON in the list batcher:pending.
+type RedisPendingQueue struct {
+	client *redis.Client
+}
+
+func NewRedisPendingQueue(cfg config.RedisConfig) (PendingQueueRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisPendingQueue{client: rdb}, nil
+}
+
+func (q *RedisPendingQueue) Push(ctx context.Context, requests []models.PendingRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(requests))
+	for _, request := range requests {
+		jsonData, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to marshal pending request: %w", err)
+		}
+		values = append(values, jsonData)
+	}
+
+	if err := q.client.RPush(ctx, pendingQueueKey, values...).Err(); err != nil {
+		return fmt.Errorf("failed to push pending requests: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisPendingQueue) PopAll(ctx context.Context) ([]models.PendingRequest, error) {
+	var values *redis.StringSliceCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.LRange(ctx, pendingQueueKey, 0, -1)
+		pipe.Del(ctx, pendingQueueKey)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop pending requests: %w", err)
+	}
+
+	// The list is already deleted, so a malformed entry is skipped rather
+	// than failing the whole pop and losing the others with it.
+	requests := make([]models.PendingRequest, 0, len(values.Val()))
+	for _, value := range values.Val() {
+		var request models.PendingRequest
+		if err := json.Unmarshal([]byte(value), &request); err != nil {
+			log.Printf("repository.RedisPendingQueue.PopAll.Malformed: %q: %v", value, err)
+			continue
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func (q *RedisPendingQueue) Close() error {
+	return q.client.Close()
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+)
+
+// takeTokenScript refills the bucket for the time passed since the last call
+// and takes a token, or returns the milliseconds until one is available.
+// Time comes from Redis, so replicas with skewed clocks share one bucket.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait`)
+
+// acquireSessionScript frees expired slots and takes one if fewer than the
+// limit are held. Slots are scored by their expiry in Redis milliseconds.
+var acquireSessionScript = redis.NewScript(`
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local ttl = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+
+redis.call("ZADD", KEYS[1], now + ttl, ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ttl)
+return 1`)
+
+// RedisRateLimitStore keeps the token bucket of each provider in the hash
+// ratelimit:{provider}:tokens and its session slots in the sorted set
+// ratelimit:{provider}:sessions.
+type RedisRateLimitStore struct {
+	client *redis.Client
+}
+
+func NewRedisRateLimitStore(cfg config.RedisConfig) (RateLimitRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisRateLimitStore{client: rdb}, nil
+}
+
+func (s *RedisRateLimitStore) TakeToken(ctx context.Context, provider string, rate float64, burst int) (time.Duration, error) {
+	args := []interface{}{strconv.FormatFloat(rate, 'f', -1, 64), burst}
+	waitMs, err := takeTokenScript.Run(ctx, s.client, []string{tokensKey(provider)}, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return time.Duration(waitMs) * time.Millisecond, nil
+}
+
+func (s *RedisRateLimitStore) AcquireSession(ctx context.Context, provider, holder string, limit int, ttl time.Duration) (bool, error) {
+	args := []interface{}{holder, limit, ttl.Milliseconds()}
+	acquired, err := acquireSessionScript.Run(ctx, s.client, []string{sessionsKey(provider)}, args...).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire session slot: %w", err)
+	}
+
+	return acquired == 1, nil
+}
+
+func (s *RedisRateLimitStore) ReleaseSession(ctx context.Context, provider, holder string) error {
+	if err := s.client.ZRem(ctx, sessionsKey(provider), holder).Err(); err != nil {
+		return fmt.Errorf("failed to release session slot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisRateLimitStore) Close() error {
+	return s.client.Close()
+}
+
+func tokensKey(provider string) string {
+	return "ratelimit:" + provider + ":tokens"
+}
+
+func sessionsKey(provider string) string {
+	return "ratelimit:" + provider + ":sessions"
+}
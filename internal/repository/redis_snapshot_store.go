@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+const snapshotIndexKey = "snapshots:index"
+
+// RedisSnapshotStore keeps snapshot parts under snapshot:{id}:* keys with a
+// TTL equal to the retention, and a sorted set index ordered by creation time.
+type RedisSnapshotStore struct {
+	client    *redis.Client
+	maxCount  int
+	retention time.Duration
+}
+
+func NewRedisSnapshotStore(cfg config.RedisConfig, maxCount int, retention time.Duration) (SnapshotRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisSnapshotStore{
+		client:    rdb,
+		maxCount:  maxCount,
+		retention: retention,
+	}, nil
+}
+
+func snapshotKey(id, part string) string {
+	return fmt.Sprintf("snapshot:%s:%s", id, part)
+}
+
+func (s *RedisSnapshotStore) Save(ctx context.Context, snapshot *models.Snapshot, html string, screenshot []byte) error {
+	meta, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, snapshotKey(snapshot.ID, "meta"), meta, s.retention)
+	pipe.Set(ctx, snapshotKey(snapshot.ID, "html"), html, s.retention)
+	if len(screenshot) > 0 {
+		pipe.Set(ctx, snapshotKey(snapshot.ID, "png"), screenshot, s.retention)
+	}
+	pipe.ZAdd(ctx, snapshotIndexKey, redis.Z{
+		Score:  float64(snapshot.CreatedAt.UnixNano()),
+		Member: snapshot.ID,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	return s.prune(ctx)
+}
+
+func (s *RedisSnapshotStore) List(ctx context.Context, batchID string) ([]models.Snapshot, error) {
+	ids, err := s.client.ZRevRange(ctx, snapshotIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	snapshots := make([]models.Snapshot, 0, len(ids))
+	for _, id := range ids {
+		meta, err := s.client.Get(ctx, snapshotKey(id, "meta")).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load snapshot %s: %w", id, err)
+		}
+
+		var snapshot models.Snapshot
+		if err := json.Unmarshal([]byte(meta), &snapshot); err != nil {
+			continue
+		}
+		if batchID == "" || snapshot.BatchID == batchID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (s *RedisSnapshotStore) GetHTML(ctx context.Context, id string) (string, error) {
+	content, err := s.client.Get(ctx, snapshotKey(id, "html")).Result()
+	if err == redis.Nil {
+		return "", ErrSnapshotNotFound
+	}
+	return content, err
+}
+
+func (s *RedisSnapshotStore) GetScreenshot(ctx context.Context, id string) ([]byte, error) {
+	content, err := s.client.Get(ctx, snapshotKey(id, "png")).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSnapshotNotFound
+	}
+	return content, err
+}
+
+// prune drops index entries past the retention window and the oldest entries
+// beyond maxCount together with their keys.
+func (s *RedisSnapshotStore) prune(ctx context.Context) error {
+	if s.retention > 0 {
+		cutoff := time.Now().Add(-s.retention).UnixNano()
+		if err := s.client.ZRemRangeByScore(ctx, snapshotIndexKey, "-inf", fmt.Sprintf("(%d", cutoff)).Err(); err != nil {
+			return fmt.Errorf("failed to prune snapshots: %w", err)
+		}
+	}
+
+	if s.maxCount <= 0 {
+		return nil
+	}
+
+	stale, err := s.client.ZRange(ctx, snapshotIndexKey, 0, int64(-s.maxCount-1)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(stale)*3)
+	members := make([]interface{}, 0, len(stale))
+	for _, id := range stale {
+		keys = append(keys, snapshotKey(id, "meta"), snapshotKey(id, "html"), snapshotKey(id, "png"))
+		members = append(members, id)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.ZRem(ctx, snapshotIndexKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+const watchDueKey = "watch:due"
+
+// releaseLeaseScript deletes a lease only if it is still held by the caller.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisWatchStore keeps each watch as JSON under watch:code:{code}, a sorted
+// set watch:due scored by the next check in unix milliseconds and leases under
+// watch:lease:{code}. Each kind of key has its own prefix, so no code can
+// collide with the index.
+type RedisWatchStore struct {
+	client *redis.Client
+}
+
+func NewRedisWatchStore(cfg config.RedisConfig) (WatchRepository, error) {
+	rdb, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisWatchStore{client: rdb}, nil
+}
+
+func watchKey(trackCode string) string {
+	return fmt.Sprintf("watch:code:%s", trackCode)
+}
+
+func leaseKey(trackCode string) string {
+	return fmt.Sprintf("watch:lease:%s", trackCode)
+}
+
+func (s *RedisWatchStore) Add(ctx context.Context, watch *models.Watch) (*models.Watch, error) {
+	jsonData, err := json.Marshal(watch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal watch: %w", err)
+	}
+
+	added, err := s.client.SetNX(ctx, watchKey(watch.TrackCode), jsonData, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to add watch: %w", err)
+	}
+	if !added {
+		return s.Get(ctx, watch.TrackCode)
+	}
+
+	if err := s.client.ZAdd(ctx, watchDueKey, redis.Z{
+		Score:  float64(watch.NextCheck.UnixMilli()),
+		Member: watch.TrackCode,
+	}).Err(); err != nil {
+		return nil, fmt.Errorf("failed to schedule watch: %w", err)
+	}
+
+	return watch, nil
+}
+
+func (s *RedisWatchStore) Get(ctx context.Context, trackCode string) (*models.Watch, error) {
+	val, err := s.client.Get(ctx, watchKey(trackCode)).Result()
+	if err == redis.Nil {
+		return nil, ErrWatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watch: %w", err)
+	}
+
+	var watch models.Watch
+	if err := json.Unmarshal([]byte(val), &watch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal watch: %w", err)
+	}
+
+	return &watch, nil
+}
+
+func (s *RedisWatchStore) Save(ctx context.Context, watch *models.Watch) error {
+	jsonData, err := json.Marshal(watch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, watchKey(watch.TrackCode), jsonData, 0)
+	pipe.ZAdd(ctx, watchDueKey, redis.Z{
+		Score:  float64(watch.NextCheck.UnixMilli()),
+		Member: watch.TrackCode,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisWatchStore) Remove(ctx context.Context, trackCode string) error {
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, watchKey(trackCode))
+	pipe.ZRem(ctx, watchDueKey, trackCode)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove watch: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisWatchStore) Due(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	codes, err := s.client.ZRangeByScore(ctx, watchDueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due watches: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (s *RedisWatchStore) Count(ctx context.Context) (int64, error) {
+	return s.client.ZCard(ctx, watchDueKey).Result()
+}
+
+func (s *RedisWatchStore) AcquireLease(ctx context.Context, trackCode, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := s.client.SetNX(ctx, leaseKey(trackCode), owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+
+	return acquired, nil
+}
+
+func (s *RedisWatchStore) ReleaseLease(ctx context.Context, trackCode, owner string) error {
+	if err := releaseLeaseScript.Run(ctx, s.client, []string{leaseKey(trackCode)}, owner).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisWatchStore) Close() error {
+	return s.client.Close()
+}
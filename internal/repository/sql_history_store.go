@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// historyTimeLayout is fixed-width UTC, so times stored as text sort
+// chronologically in both SQLite and Postgres.
+const historyTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS track_events (
+	track_code TEXT NOT NULL,
+	event_key  TEXT NOT NULL,
+	event_at   TEXT NOT NULL,
+	code       TEXT NOT NULL,
+	payload    TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	PRIMARY KEY (track_code, event_key)
+)`
+
+// SQLHistoryStore keeps one row per distinct event of a track code. Rows are
+// never updated, so first_seen is the time the event first appeared.
+type SQLHistoryStore struct {
+	db *sql.DB
+}
+
+func NewSQLHistoryStore(cfg config.HistoryConfig) (HistoryRepository, error) {
+	var driver string
+	switch cfg.Driver {
+	case config.HistoryDriverSQLite:
+		driver = "sqlite"
+	case config.HistoryDriverPostgres:
+		driver = "pgx"
+	default:
+		return nil, fmt.Errorf("unknown history driver: %s", cfg.Driver)
+	}
+
+	db, err := sql.Open(driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	if cfg.Driver == config.HistoryDriverSQLite {
+		// SQLite allows a single writer; serialize instead of failing with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx, historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history schema: %w", err)
+	}
+
+	return &SQLHistoryStore{db: db}, nil
+}
+
+func (s *SQLHistoryStore) AddEvents(ctx context.Context, trackCode string, events []models.Event, seenAt time.Time) (int, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO track_events (track_code, event_key, event_at, code, payload, first_seen)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (track_code, event_key) DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare history insert: %w", err)
+	}
+	defer stmt.Close()
+
+	firstSeen := seenAt.UTC().Format(historyTimeLayout)
+	added := 0
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal event: %w", err)
+		}
+
+		result, err := stmt.ExecContext(ctx, trackCode, eventKey(event), eventAt(event), event.Code, string(payload), firstSeen)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert event: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit history: %w", err)
+	}
+
+	return added, nil
+}
+
+// GetHistory returns all recorded events of trackCode, newest first. Events
+// without a parseable date come last.
+func (s *SQLHistoryStore) GetHistory(ctx context.Context, trackCode string) ([]models.HistoryEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT payload, first_seen FROM track_events
+		WHERE track_code = $1
+		ORDER BY event_at DESC, first_seen DESC`, trackCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]models.HistoryEvent, 0)
+	for rows.Next() {
+		var payload, firstSeen string
+		if err := rows.Scan(&payload, &firstSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan history row: %w", err)
+		}
+
+		var event models.HistoryEvent
+		if err := json.Unmarshal([]byte(payload), &event.Event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		event.FirstSeen, _ = time.Parse(historyTimeLayout, firstSeen)
+
+		history = append(history, event)
+	}
+
+	return history, rows.Err()
+}
+
+func (s *SQLHistoryStore) Health(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+// eventKey hashes the event identity to keep the primary key short.
+func eventKey(event models.Event) string {
+	sum := sha256.Sum256([]byte(event.Key()))
+	return hex.EncodeToString(sum[:])
+}
+
+func eventAt(event models.Event) string {
+	t, err := time.Parse(time.RFC3339, event.Date)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(historyTimeLayout)
+}
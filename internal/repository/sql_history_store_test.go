@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// TestSQLHistoryStore - сохранение только новых событий и время первого появления
+func TestSQLHistoryStore(t *testing.T) {
+	store, err := NewSQLHistoryStore(config.HistoryConfig{
+		Driver: config.HistoryDriverSQLite,
+		DSN:    filepath.Join(t.TempDir(), "history.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	firstSeen := time.Date(2025, 9, 18, 4, 0, 0, 0, time.UTC)
+
+	accepted := models.Event{Status: "Accepted", Date: "2025-09-16T09:02:31+08:00", Code: models.EventAccepted}
+	customs := models.Event{Status: "Customs clearance completed", Date: "2025-09-17T20:10:04+08:00", Code: models.EventCustomsCleared}
+
+	added, err := store.AddEvents(ctx, "LK517880262CN", []models.Event{accepted, customs}, firstSeen)
+	if err != nil || added != 2 {
+		t.Fatalf("AddEvents = %d, %v, want 2", added, err)
+	}
+
+	// The same customs event reported in UTC, plus a new delivery event.
+	customsUTC := customs
+	customsUTC.Date = "2025-09-17T12:10:04Z"
+	delivered := models.Event{Status: "Delivered", Date: "2025-09-20T10:00:00+06:00", Code: models.EventDelivered}
+
+	added, err = store.AddEvents(ctx, "LK517880262CN", []models.Event{delivered, customsUTC}, firstSeen.Add(time.Hour))
+	if err != nil || added != 1 {
+		t.Fatalf("AddEvents = %d, %v, want 1", added, err)
+	}
+
+	history, err := store.GetHistory(ctx, "LK517880262CN")
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+
+	want := []string{models.EventDelivered, models.EventCustomsCleared, models.EventAccepted}
+	if len(history) != len(want) {
+		t.Fatalf("got %d events, want %d", len(history), len(want))
+	}
+	for i, code := range want {
+		if history[i].Code != code {
+			t.Errorf("event %d code = %s, want %s", i, history[i].Code, code)
+		}
+	}
+	if !history[1].FirstSeen.Equal(firstSeen) {
+		t.Errorf("customs first seen = %s, want %s", history[1].FirstSeen, firstSeen)
+	}
+
+	other, err := store.GetHistory(ctx, "UNKNOWN")
+	if err != nil || len(other) != 0 {
+		t.Errorf("GetHistory(UNKNOWN) = %v, %v, want empty", other, err)
+	}
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// Reasons a watch is dropped.
+const (
+	stopDelivered = "delivered"
+	stopReturned  = "returned"
+	stopMaxAge    = "max_age"
+)
+
+// nextInterval decides when a watched code is checked again after a refresh
+// that returned data (nil on error). The interval is a quarter of the time
+// since the last event, so a parcel that moved an hour ago is checked often
+// and one silent for days rarely. A non-empty stop reason ends the watch.
+func nextInterval(cfg config.SchedulerConfig, watch *models.Watch, data *models.TrackData, now time.Time) (time.Duration, string) {
+	if cfg.MaxAge > 0 && now.Sub(watch.AddedAt) >= cfg.MaxAge {
+		return 0, stopMaxAge
+	}
+	if data == nil {
+		return cfg.MinInterval, ""
+	}
+
+	reference := watch.AddedAt
+	if data.LastEvent != nil {
+		switch data.LastEvent.Code {
+		case models.EventDelivered:
+			return 0, stopDelivered
+		case models.EventReturned:
+			return 0, stopReturned
+		case models.EventOutForDelivery, models.EventDeliveryFailed, models.EventCustoms, models.EventException:
+			return cfg.MinInterval, ""
+		}
+
+		if at, err := time.Parse(time.RFC3339, data.LastEvent.Date); err == nil {
+			reference = at
+		}
+	}
+
+	interval := now.Sub(reference) / 4
+	if interval < cfg.MinInterval {
+		interval = cfg.MinInterval
+	}
+	if cfg.MaxInterval > 0 && interval > cfg.MaxInterval {
+		interval = cfg.MaxInterval
+	}
+
+	return interval, ""
+}
+
+// withJitter spreads checks by randomizing interval by ±ratio.
+func withJitter(interval time.Duration, ratio float64) time.Duration {
+	if ratio <= 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * (1 + ratio*(2*rand.Float64()-1)))
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// TestNextInterval - адаптивный интервал и условия остановки наблюдения
+func TestNextInterval(t *testing.T) {
+	cfg := config.SchedulerConfig{
+		MinInterval: time.Hour,
+		MaxInterval: 12 * time.Hour,
+		MaxAge:      30 * 24 * time.Hour,
+	}
+	now := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	watch := &models.Watch{AddedAt: now.Add(-48 * time.Hour)}
+
+	withLast := func(code string, age time.Duration) *models.TrackData {
+		event := models.Event{Code: code, Date: now.Add(-age).Format(time.RFC3339)}
+		return &models.TrackData{Events: []models.Event{event}, LastEvent: &event}
+	}
+
+	tests := []struct {
+		name     string
+		watch    *models.Watch
+		data     *models.TrackData
+		want     time.Duration
+		wantStop string
+	}{
+		{name: "error retries soon", watch: watch, data: nil, want: time.Hour},
+		{name: "recent event", watch: watch, data: withLast(models.EventDeparted, 2*time.Hour), want: time.Hour},
+		{name: "quiet for a day", watch: watch, data: withLast(models.EventInTransit, 24*time.Hour), want: 6 * time.Hour},
+		{name: "quiet for a week", watch: watch, data: withLast(models.EventInTransit, 7*24*time.Hour), want: 12 * time.Hour},
+		{name: "out for delivery", watch: watch, data: withLast(models.EventOutForDelivery, 24*time.Hour), want: time.Hour},
+		{name: "no events yet", watch: watch, data: &models.TrackData{}, want: 12 * time.Hour},
+		{name: "delivered", watch: watch, data: withLast(models.EventDelivered, time.Hour), wantStop: stopDelivered},
+		{name: "returned", watch: watch, data: withLast(models.EventReturned, time.Hour), wantStop: stopReturned},
+		{name: "too old", watch: &models.Watch{AddedAt: now.Add(-31 * 24 * time.Hour)}, data: withLast(models.EventInTransit, time.Hour), wantStop: stopMaxAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stop := nextInterval(cfg, tt.watch, tt.data, now)
+			if stop != tt.wantStop {
+				t.Fatalf("stop = %q, want %q", stop, tt.wantStop)
+			}
+			if stop == "" && got != tt.want {
+				t.Errorf("interval = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWithJitter - разброс интервала в пределах заданной доли
+func TestWithJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		got := withJitter(10*time.Hour, 0.2)
+		if got < 8*time.Hour || got > 12*time.Hour {
+			t.Fatalf("withJitter = %s, want within 8h..12h", got)
+		}
+	}
+	if got := withJitter(time.Hour, 0); got != time.Hour {
+		t.Errorf("withJitter without ratio = %s", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Package scheduler refreshes watched track codes in the background by feeding
+// them into the batcher. Watches and leases live in Redis, so any number of
+// replicas can run a scheduler and each due code is refreshed by one of them.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	mathrand "math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/batcher"
+	"github.com/shamil/proxy_track_service-1/internal/clock"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+// Enqueuer accepts track codes for fetching, as the batcher does.
+type Enqueuer interface {
+	AddRequest(ctx context.Context, trackCode string, priority batcher.Priority) <-chan models.TrackResponse
+}
+
+type Scheduler struct {
+	config   config.SchedulerConfig
+	store    repository.WatchRepository
+	enqueuer Enqueuer
+	owner    string
+	clock    clock.Clock
+
+	wg sync.WaitGroup
+	// stopCtx is cancelled by Stop and cancels the loop and every refresh in
+	// flight, so stopping never waits out a LeaseTTL.
+	stopCtx context.Context
+	stop    context.CancelFunc
+}
+
+type Option func(*Scheduler)
+
+// WithClock runs ticks and next-check times on c instead of the system
+// clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+func New(cfg config.SchedulerConfig, store repository.WatchRepository, enqueuer Enqueuer, opts ...Option) *Scheduler {
+	stopCtx, stop := context.WithCancel(context.Background())
+	s := &Scheduler{
+		config:   cfg,
+		store:    store,
+		enqueuer: enqueuer,
+		owner:    newOwnerID(),
+		clock:    clock.Real(),
+		stopCtx:  stopCtx,
+		stop:     stop,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Watch starts refreshing trackCode. The first check lands at a random point
+// within MinInterval so codes added together do not refresh together.
+func (s *Scheduler) Watch(ctx context.Context, trackCode string) (*models.Watch, error) {
+	now := s.clock.Now()
+	watch := &models.Watch{
+		TrackCode: trackCode,
+		AddedAt:   now,
+		NextCheck: now.Add(time.Duration(mathrand.Int63n(int64(s.config.MinInterval) + 1))),
+	}
+
+	return s.store.Add(ctx, watch)
+}
+
+func (s *Scheduler) Unwatch(ctx context.Context, trackCode string) error {
+	if _, err := s.store.Get(ctx, trackCode); err != nil {
+		return err
+	}
+	return s.store.Remove(ctx, trackCode)
+}
+
+func (s *Scheduler) Get(ctx context.Context, trackCode string) (*models.Watch, error) {
+	return s.store.Get(ctx, trackCode)
+}
+
+// Count returns how many codes are watched across all replicas.
+func (s *Scheduler) Count(ctx context.Context) (int64, error) {
+	return s.store.Count(ctx)
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.loop(ctx)
+	log.Printf("Scheduler started as %s", s.owner)
+}
+
+// Stop ends the loop, cancels refreshes in flight and waits for them to put
+// their codes back.
+func (s *Scheduler) Stop() {
+	s.stop()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(s.config.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			s.tick(ctx)
+		case <-ctx.Done():
+			return
+		case <-s.stopCtx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	codes, err := s.store.Due(ctx, s.clock.Now(), s.config.BatchLimit)
+	if err != nil {
+		log.Printf("scheduler.tick.Error: %v", err)
+		return
+	}
+
+	for _, code := range codes {
+		acquired, err := s.store.AcquireLease(ctx, code, s.owner, s.config.LeaseTTL)
+		if err != nil {
+			log.Printf("scheduler.tick.LeaseError: %s: %v", code, err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.refresh(ctx, code)
+	}
+}
+
+// refresh sends one code through the batcher and reschedules or drops it.
+// The lease covers the whole refresh; if this replica dies the lease expires
+// and the still-due code is picked up elsewhere.
+func (s *Scheduler) refresh(ctx context.Context, trackCode string) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.LeaseTTL)
+	defer cancel()
+	defer context.AfterFunc(s.stopCtx, cancel)()
+	defer func() {
+		if err := s.store.ReleaseLease(context.Background(), trackCode, s.owner); err != nil {
+			log.Printf("scheduler.refresh.ReleaseError: %s: %v", trackCode, err)
+		}
+	}()
+
+	watch, err := s.store.Get(ctx, trackCode)
+	if errors.Is(err, repository.ErrWatchNotFound) {
+		// Due without a watch, e.g. left over from an older key layout:
+		// drop it from the index so it is not picked up on every tick.
+		log.Printf("scheduler.refresh.Orphan: %s", trackCode)
+		if err := s.store.Remove(ctx, trackCode); err != nil {
+			log.Printf("scheduler.refresh.RemoveError: %s: %v", trackCode, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("scheduler.refresh.LoadError: %s: %v", trackCode, err)
+		return
+	}
+
+	// Move the code out of the due range while it is being refreshed, so
+	// later ticks do not spend their limit on it.
+	due := watch.NextCheck
+	watch.NextCheck = s.clock.Now().Add(s.config.LeaseTTL)
+	if err := s.store.Save(ctx, watch); err != nil {
+		log.Printf("scheduler.refresh.SaveError: %s: %v", trackCode, err)
+		return
+	}
+
+	var data *models.TrackData
+	select {
+	case response := <-s.enqueuer.AddRequest(ctx, trackCode, batcher.PriorityBulk):
+		if response.Status {
+			data = response.Data
+		} else {
+			metrics.SchedulerErrors.Add(1)
+			log.Printf("scheduler.refresh.Error: %s: %s", trackCode, response.Error)
+		}
+	case <-ctx.Done():
+		if s.stopCtx.Err() != nil {
+			// Stopped mid-refresh: make the code due again right away so
+			// another replica picks it up instead of waiting out the lease.
+			log.Printf("scheduler.refresh.Cancelled: %s", trackCode)
+			watch.NextCheck = due
+			if err := s.store.Save(context.Background(), watch); err != nil {
+				log.Printf("scheduler.refresh.SaveError: %s: %v", trackCode, err)
+			}
+			return
+		}
+		metrics.SchedulerErrors.Add(1)
+		log.Printf("scheduler.refresh.Timeout: %s", trackCode)
+	}
+	metrics.SchedulerRefreshes.Add(1)
+
+	now := s.clock.Now()
+	interval, stop := nextInterval(s.config, watch, data, now)
+	if stop != "" {
+		metrics.SchedulerStopped.Add(stop, 1)
+		log.Printf("scheduler.refresh.Stopped: %s: %s", trackCode, stop)
+		if err := s.store.Remove(context.Background(), trackCode); err != nil {
+			log.Printf("scheduler.refresh.RemoveError: %s: %v", trackCode, err)
+		}
+		return
+	}
+
+	if _, err := s.store.Get(context.Background(), trackCode); errors.Is(err, repository.ErrWatchNotFound) {
+		return // unwatched during the refresh
+	}
+
+	watch.Checks++
+	watch.LastChecked = &now
+	if data != nil && data.LastEvent != nil {
+		watch.LastCode = data.LastEvent.Code
+	}
+	watch.NextCheck = now.Add(withJitter(interval, s.config.Jitter))
+
+	if err := s.store.Save(context.Background(), watch); err != nil {
+		log.Printf("scheduler.refresh.SaveError: %s: %v", trackCode, err)
+	}
+}
+
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,311 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/batcher"
+	"github.com/shamil/proxy_track_service-1/internal/clock"
+	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/models"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+type memoryWatchStore struct {
+	mu      sync.Mutex
+	watches map[string]models.Watch
+	leases  map[string]string
+}
+
+func newMemoryWatchStore() *memoryWatchStore {
+	return &memoryWatchStore{
+		watches: make(map[string]models.Watch),
+		leases:  make(map[string]string),
+	}
+}
+
+func (m *memoryWatchStore) Add(ctx context.Context, watch *models.Watch) (*models.Watch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.watches[watch.TrackCode]; ok {
+		return &existing, nil
+	}
+	m.watches[watch.TrackCode] = *watch
+	return watch, nil
+}
+
+func (m *memoryWatchStore) Get(ctx context.Context, trackCode string) (*models.Watch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	watch, ok := m.watches[trackCode]
+	if !ok {
+		return nil, repository.ErrWatchNotFound
+	}
+	return &watch, nil
+}
+
+func (m *memoryWatchStore) Save(ctx context.Context, watch *models.Watch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.watches[watch.TrackCode] = *watch
+	return nil
+}
+
+func (m *memoryWatchStore) Remove(ctx context.Context, trackCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.watches, trackCode)
+	return nil
+}
+
+func (m *memoryWatchStore) Due(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code, watch := range m.watches {
+		if !watch.NextCheck.After(now) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if len(codes) > limit {
+		codes = codes[:limit]
+	}
+	return codes, nil
+}
+
+func (m *memoryWatchStore) Count(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.watches)), nil
+}
+
+func (m *memoryWatchStore) AcquireLease(ctx context.Context, trackCode, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, held := m.leases[trackCode]; held {
+		return false, nil
+	}
+	m.leases[trackCode] = owner
+	return true, nil
+}
+
+func (m *memoryWatchStore) ReleaseLease(ctx context.Context, trackCode, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leases[trackCode] == owner {
+		delete(m.leases, trackCode)
+	}
+	return nil
+}
+
+func (m *memoryWatchStore) Close() error {
+	return nil
+}
+
+type countingEnqueuer struct {
+	mu       sync.Mutex
+	requests map[string]int
+	code     string
+}
+
+func (c *countingEnqueuer) AddRequest(ctx context.Context, trackCode string, priority batcher.Priority) <-chan models.TrackResponse {
+	c.mu.Lock()
+	c.requests[trackCode]++
+	c.mu.Unlock()
+
+	event := models.Event{Code: c.code, Date: time.Now().Format(time.RFC3339)}
+	respChan := make(chan models.TrackResponse, 1)
+	respChan <- models.TrackResponse{
+		Status: true,
+		Data:   &models.TrackData{Events: []models.Event{event}, LastEvent: &event},
+	}
+	return respChan
+}
+
+// TestSchedulerLease - две реплики с общим хранилищем обновляют каждый код один раз
+func TestSchedulerLease(t *testing.T) {
+	cfg := config.SchedulerConfig{
+		BatchLimit:  10,
+		MinInterval: time.Hour,
+		MaxInterval: 12 * time.Hour,
+		LeaseTTL:    time.Minute,
+	}
+	store := newMemoryWatchStore()
+	enqueuer := &countingEnqueuer{requests: make(map[string]int), code: models.EventInTransit}
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	for _, code := range []string{"WATCH001", "WATCH002", "WATCH003"} {
+		store.Add(ctx, &models.Watch{TrackCode: code, AddedAt: past, NextCheck: past})
+	}
+
+	replicas := []*Scheduler{New(cfg, store, enqueuer), New(cfg, store, enqueuer)}
+
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func(s *Scheduler) {
+			defer wg.Done()
+			s.tick(ctx)
+		}(replica)
+	}
+	wg.Wait()
+	for _, replica := range replicas {
+		replica.Stop()
+	}
+
+	for _, code := range []string{"WATCH001", "WATCH002", "WATCH003"} {
+		if n := enqueuer.requests[code]; n != 1 {
+			t.Errorf("%s refreshed %d times, want 1", code, n)
+		}
+
+		watch, err := store.Get(ctx, code)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", code, err)
+		}
+		if watch.Checks != 1 || watch.LastCode != models.EventInTransit {
+			t.Errorf("%s watch = %+v", code, watch)
+		}
+		if !watch.NextCheck.After(time.Now().Add(30 * time.Minute)) {
+			t.Errorf("%s next check %s is too soon", code, watch.NextCheck)
+		}
+	}
+
+	if due, _ := store.Due(ctx, time.Now(), 10); len(due) != 0 {
+		t.Errorf("codes still due after refresh: %v", due)
+	}
+}
+
+// TestSchedulerStopsDelivered - доставленная посылка снимается с наблюдения
+func TestSchedulerStopsDelivered(t *testing.T) {
+	cfg := config.SchedulerConfig{BatchLimit: 10, MinInterval: time.Hour, LeaseTTL: time.Minute}
+	store := newMemoryWatchStore()
+	enqueuer := &countingEnqueuer{requests: make(map[string]int), code: models.EventDelivered}
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	store.Add(ctx, &models.Watch{TrackCode: "DONE001", AddedAt: past, NextCheck: past})
+
+	s := New(cfg, store, enqueuer)
+	s.tick(ctx)
+	s.Stop()
+
+	if _, err := store.Get(ctx, "DONE001"); err != repository.ErrWatchNotFound {
+		t.Errorf("delivered watch still stored, err = %v", err)
+	}
+}
+
+// blockingEnqueuer never answers, like a batcher stuck on a slow provider.
+type blockingEnqueuer struct {
+	requested chan string
+}
+
+func (b *blockingEnqueuer) AddRequest(ctx context.Context, trackCode string, priority batcher.Priority) <-chan models.TrackResponse {
+	b.requested <- trackCode
+	return make(chan models.TrackResponse)
+}
+
+// TestSchedulerStopCancelsRefresh - Stop прерывает зависшее обновление и возвращает код в очередь
+func TestSchedulerStopCancelsRefresh(t *testing.T) {
+	cfg := config.SchedulerConfig{BatchLimit: 10, MinInterval: time.Hour, LeaseTTL: time.Hour}
+	store := newMemoryWatchStore()
+	enqueuer := &blockingEnqueuer{requested: make(chan string, 1)}
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	store.Add(ctx, &models.Watch{TrackCode: "STUCK001", AddedAt: past, NextCheck: past})
+
+	s := New(cfg, store, enqueuer)
+	s.tick(ctx)
+	<-enqueuer.requested
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for the refresh lease")
+	}
+
+	watch, err := store.Get(ctx, "STUCK001")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !watch.NextCheck.Equal(past) || watch.Checks != 0 {
+		t.Errorf("cancelled watch = %+v, want it due again at %s", watch, past)
+	}
+	if _, held := store.leases["STUCK001"]; held {
+		t.Error("lease still held after Stop")
+	}
+}
+
+// tickStore reports the time of every Due call, so tests know when a tick ran.
+type tickStore struct {
+	*memoryWatchStore
+	ticks chan time.Time
+}
+
+func (s *tickStore) Due(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	codes, err := s.memoryWatchStore.Due(ctx, now, limit)
+	s.ticks <- now
+	return codes, err
+}
+
+// TestSchedulerFakeClock - тики и время следующей проверки идут по переданным часам
+func TestSchedulerFakeClock(t *testing.T) {
+	cfg := config.SchedulerConfig{
+		TickInterval: time.Minute,
+		BatchLimit:   10,
+		MinInterval:  time.Hour,
+		MaxInterval:  12 * time.Hour,
+		LeaseTTL:     time.Minute,
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	store := &tickStore{memoryWatchStore: newMemoryWatchStore(), ticks: make(chan time.Time, 1)}
+	enqueuer := &countingEnqueuer{requests: make(map[string]int), code: models.EventInTransit}
+
+	ctx := context.Background()
+	next := start.Add(90 * time.Second)
+	store.Add(ctx, &models.Watch{TrackCode: "CLOCK001", AddedAt: start, NextCheck: next})
+
+	s := New(cfg, store, enqueuer, WithClock(fake))
+	s.Start(ctx)
+	fake.BlockUntil(1)
+
+	fake.Advance(time.Minute)
+	if now := <-store.ticks; !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("first tick at %s, want %s", now, start.Add(time.Minute))
+	}
+
+	fake.Advance(time.Minute)
+	<-store.ticks
+	s.Stop()
+
+	if n := enqueuer.requests["CLOCK001"]; n != 1 {
+		t.Fatalf("refreshed %d times, want once on the second tick", n)
+	}
+	watch, err := store.Get(ctx, "CLOCK001")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if watch.LastChecked == nil || !watch.LastChecked.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("last checked = %v, want %s", watch.LastChecked, start.Add(2*time.Minute))
+	}
+}
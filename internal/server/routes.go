@@ -2,14 +2,19 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/shamil/proxy_track_service-1/internal/handler"
+	"github.com/shamil/proxy_track_service-1/internal/metrics"
 	"github.com/shamil/proxy_track_service-1/internal/service"
 )
 
-func SetupRoutes(trackingService service.TrackingService) *mux.Router {
+// SetupRoutes builds the router. The admin routes and /metrics, which exposes
+// the process command line and memory stats, are served only when adminToken
+// is set and require it as a bearer token.
+func SetupRoutes(trackingService service.TrackingService, adminHandler *handler.AdminHandler, adminToken string) *mux.Router {
 	router := mux.NewRouter()
 	router.Use(handler.LoggingMiddleware)
 	router.Use(handler.CORSMiddleware)
@@ -18,6 +23,7 @@ func SetupRoutes(trackingService service.TrackingService) *mux.Router {
 	trackHandler := handler.NewTrackHandler(trackingService)
 
 	setupAPIRoutes(router, trackHandler)
+	setupAdminRoutes(router, adminHandler, adminToken)
 	setupGeneralRoutes(router)
 
 	return router
@@ -25,9 +31,31 @@ func SetupRoutes(trackingService service.TrackingService) *mux.Router {
 
 func setupAPIRoutes(router *mux.Router, trackHandler *handler.TrackHandler) {
 	router.HandleFunc("/track/{trackCode}", trackHandler.GetTrackStatus).Methods("GET")
+	router.HandleFunc("/track/{trackCode}/history", trackHandler.GetTrackHistory).Methods("GET")
+	router.HandleFunc("/track/{trackCode}/changes", trackHandler.GetTrackChanges).Methods("GET")
+	router.HandleFunc("/watch/{trackCode}", trackHandler.WatchTrack).Methods("POST")
+	router.HandleFunc("/watch/{trackCode}", trackHandler.GetWatch).Methods("GET")
+	router.HandleFunc("/watch/{trackCode}", trackHandler.UnwatchTrack).Methods("DELETE")
 	router.HandleFunc("/health", trackHandler.HealthCheck).Methods("GET")
 }
 
+func setupAdminRoutes(router *mux.Router, adminHandler *handler.AdminHandler, adminToken string) {
+	if adminToken == "" {
+		log.Println("server.setupAdminRoutes.Disabled: ADMIN_TOKEN is not set")
+		return
+	}
+
+	auth := handler.AdminAuthMiddleware(adminToken)
+	router.Handle("/metrics", auth(metrics.Handler())).Methods("GET")
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(auth)
+	admin.HandleFunc("/proxies", adminHandler.GetProxies).Methods("GET")
+	admin.HandleFunc("/snapshots", adminHandler.ListSnapshots).Methods("GET")
+	admin.HandleFunc("/snapshots/{id}/html", adminHandler.GetSnapshotHTML).Methods("GET")
+	admin.HandleFunc("/snapshots/{id}/screenshot", adminHandler.GetSnapshotScreenshot).Methods("GET")
+}
+
 func setupGeneralRoutes(router *mux.Router) {
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -37,7 +65,13 @@ func setupGeneralRoutes(router *mux.Router) {
 			"version": "1.0.0",
 			"endpoints": {
 				"track": "GET /track/{trackCode}",
-				"health": "GET /health"
+				"history": "GET /track/{trackCode}/history",
+				"changes": "GET /track/{trackCode}/changes?since=",
+				"watch": "POST|GET|DELETE /watch/{trackCode}",
+				"health": "GET /health",
+				"metrics": "GET /metrics",
+				"proxies": "GET /admin/proxies",
+				"snapshots": "GET /admin/snapshots"
 			}
 		}`)
 	}).Methods("GET")
@@ -50,7 +84,17 @@ func setupGeneralRoutes(router *mux.Router) {
 			"path": "%s",
 			"available_endpoints": [
 				"GET /track/{trackCode}",
-				"GET /health"
+				"GET /track/{trackCode}/history",
+				"GET /track/{trackCode}/changes",
+				"POST /watch/{trackCode}",
+				"GET /watch/{trackCode}",
+				"DELETE /watch/{trackCode}",
+				"GET /health",
+				"GET /metrics",
+				"GET /admin/proxies",
+				"GET /admin/snapshots",
+				"GET /admin/snapshots/{id}/html",
+				"GET /admin/snapshots/{id}/screenshot"
 			]
 		}`, r.URL.Path)
 	})
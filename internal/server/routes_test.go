@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/handler"
+	"github.com/shamil/proxy_track_service-1/internal/repository"
+)
+
+// TestAdminRoutesAuth - админские маршруты и /metrics требуют токен и выключены без него
+func TestAdminRoutesAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "disabled without token", authorization: "Bearer secret", want: http.StatusNotFound},
+		{name: "missing header", token: "secret", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "secret", authorization: "Basic secret", want: http.StatusUnauthorized},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", want: http.StatusOK},
+	}
+
+	for _, path := range []string{"/admin/proxies", "/metrics"} {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				router := SetupRoutes(nil, handler.NewAdminHandler(nil, nil), tt.token)
+
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				if rec.Code != tt.want {
+					t.Errorf("status = %d, want %d", rec.Code, tt.want)
+				}
+				if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate")
+				}
+			})
+		}
+	}
+}
+
+// TestSnapshotDownloadID - скачивание снимка принимает только id в формате хранилища
+func TestSnapshotDownloadID(t *testing.T) {
+	snapshots, err := repository.NewFileSnapshotStore(t.TempDir(), 10, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileSnapshotStore: %v", err)
+	}
+	router := SetupRoutes(nil, handler.NewAdminHandler(nil, snapshots), "secret")
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "unknown id", path: "/admin/snapshots/0123456789abcdef/html", want: http.StatusNotFound},
+		{name: "quote in id", path: "/admin/snapshots/x%22%3B%20a%3Db/html", want: http.StatusBadRequest},
+		{name: "uppercase id", path: "/admin/snapshots/0123456789ABCDEF/screenshot", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/handler"
 	"github.com/shamil/proxy_track_service-1/internal/service"
 )
 
@@ -15,8 +16,8 @@ type Server struct {
 	config     config.ServerConfig
 }
 
-func NewServer(config config.ServerConfig, trackingService service.TrackingService) *Server {
-	router := SetupRoutes(trackingService)
+func NewServer(config config.ServerConfig, trackingService service.TrackingService, adminHandler *handler.AdminHandler) *Server {
+	router := SetupRoutes(trackingService, adminHandler, config.AdminToken)
 
 	httpServer := &http.Server{
 		Addr:         ":" + config.Port,
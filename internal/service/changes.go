@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+// diffTrackData returns the events of current missing from previous and the
+// transition of the latest event code, or nil when nothing changed. A nil
+// previous means the first scrape: every event counts as added.
+func diffTrackData(previous, current *models.TrackData, at time.Time) *models.TrackChange {
+	known := make(map[string]bool)
+	if previous != nil {
+		for _, event := range previous.Events {
+			known[event.Key()] = true
+		}
+	}
+
+	change := &models.TrackChange{
+		DetectedAt:  at,
+		AddedEvents: make([]models.Event, 0),
+	}
+	for _, event := range current.Events {
+		if !known[event.Key()] {
+			change.AddedEvents = append(change.AddedEvents, event)
+		}
+	}
+
+	from, to := latestCode(previous), latestCode(current)
+	if from != to {
+		change.Transition = &models.StatusTransition{From: from, To: to}
+	}
+
+	if len(change.AddedEvents) == 0 && change.Transition == nil {
+		return nil
+	}
+	return change
+}
+
+func latestCode(data *models.TrackData) string {
+	if data == nil || data.LastEvent == nil {
+		return ""
+	}
+	return data.LastEvent.Code
+}
+
+func (s *trackingService) detectChanges(ctx context.Context, trackCode string, data *models.TrackData) {
+	previous, err := s.changes.SwapLatest(ctx, trackCode, data)
+	if err != nil {
+		log.Printf("service.detectChanges.Error: %s: %v", trackCode, err)
+		return
+	}
+
+	change := diffTrackData(previous, data, time.Now())
+	if change == nil {
+		return
+	}
+
+	if err := s.changes.AddChange(ctx, trackCode, change); err != nil {
+		log.Printf("service.detectChanges.Error: %s: %v", trackCode, err)
+		return
+	}
+	log.Printf("service.detectChanges: %s: %d new events", trackCode, len(change.AddedEvents))
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shamil/proxy_track_service-1/internal/models"
+)
+
+func trackData(events ...models.Event) *models.TrackData {
+	data := &models.TrackData{Events: events}
+	if len(events) > 0 {
+		data.LastEvent = &events[0]
+	}
+	return data
+}
+
+// TestDiffTrackData - новые события и смена статуса между двумя скрапами
+func TestDiffTrackData(t *testing.T) {
+	at := time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC)
+	accepted := models.Event{Status: "Accepted", Date: "2025-09-16T09:02:31+08:00", Code: models.EventAccepted}
+	departed := models.Event{Status: "Departed", Date: "2025-09-17T20:10:04+08:00", Code: models.EventDeparted}
+	departedUTC := models.Event{Status: "departed", Date: "2025-09-17T12:10:04Z", Code: models.EventDeparted}
+
+	first := diffTrackData(nil, trackData(accepted), at)
+	if first == nil || len(first.AddedEvents) != 1 || first.Transition == nil ||
+		first.Transition.From != "" || first.Transition.To != models.EventAccepted {
+		t.Errorf("first scrape change = %+v", first)
+	}
+
+	next := diffTrackData(trackData(accepted), trackData(departed, accepted), at)
+	if next == nil || len(next.AddedEvents) != 1 || next.AddedEvents[0].Status != "Departed" {
+		t.Fatalf("next scrape change = %+v", next)
+	}
+	if next.Transition == nil || next.Transition.From != models.EventAccepted || next.Transition.To != models.EventDeparted {
+		t.Errorf("transition = %+v", next.Transition)
+	}
+	if !next.DetectedAt.Equal(at) {
+		t.Errorf("DetectedAt = %s, want %s", next.DetectedAt, at)
+	}
+
+	if same := diffTrackData(trackData(departed, accepted), trackData(departedUTC, accepted), at); same != nil {
+		t.Errorf("unchanged scrape change = %+v, want nil", same)
+	}
+}
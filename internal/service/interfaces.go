@@ -2,19 +2,45 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/shamil/proxy_track_service-1/internal/batcher"
+	"github.com/shamil/proxy_track_service-1/internal/client"
 	"github.com/shamil/proxy_track_service-1/internal/config"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 )
 
 type TrackingService interface {
-	TrackPackage(ctx context.Context, trackCode string) <-chan models.TrackResponse
+	TrackPackage(ctx context.Context, trackCode string, priority batcher.Priority) <-chan models.TrackResponse
 	Start(ctx context.Context) error
 	Stop() error
+	Shutdown(ctx context.Context) error
 	Health(ctx context.Context) error
+	HealthDetails(ctx context.Context) HealthDetails
+	History(ctx context.Context, trackCode string) ([]models.HistoryEvent, error)
+	Changes(ctx context.Context, trackCode string, since time.Time) ([]models.TrackChange, error)
+	Watch(ctx context.Context, trackCode string) (*models.Watch, error)
+	Unwatch(ctx context.Context, trackCode string) error
+	GetWatch(ctx context.Context, trackCode string) (*models.Watch, error)
+}
+
+var (
+	ErrHistoryDisabled   = errors.New("tracking history is disabled")
+	ErrChangesDisabled   = errors.New("change detection is disabled")
+	ErrSchedulerDisabled = errors.New("background tracking is disabled")
+)
+
+type HealthDetails struct {
+	Provider *client.ProviderStats `json:"provider,omitempty"`
+	Batcher  *batcher.Stats        `json:"batcher,omitempty"`
+	Warnings []string              `json:"warnings,omitempty"`
+
+	WatchedCodes *int64 `json:"watched_codes,omitempty"`
 }
 
 type ServiceConfig struct {
-	BatcherConfig config.BatcherConfig
-	ClientConfig  config.ExternalConfig
+	BatcherConfig   config.BatcherConfig
+	ClientConfig    config.ExternalConfig
+	SchedulerConfig config.SchedulerConfig
 }
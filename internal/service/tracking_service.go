@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/shamil/proxy_track_service-1/internal/batcher"
 	"github.com/shamil/proxy_track_service-1/internal/client"
+	"github.com/shamil/proxy_track_service-1/internal/erors"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 	"github.com/shamil/proxy_track_service-1/internal/repository"
+	"github.com/shamil/proxy_track_service-1/internal/scheduler"
 )
 
 type trackingService struct {
@@ -17,6 +20,10 @@ type trackingService struct {
 	cache   repository.CacheRepository
 	client  client.ExternalAPIClient
 	config  ServiceConfig
+	history repository.HistoryRepository
+	changes repository.ChangeRepository
+
+	scheduler *scheduler.Scheduler
 
 	mu     sync.RWMutex
 	active bool
@@ -26,19 +33,44 @@ func NewTrackingService(
 	config ServiceConfig,
 	cache repository.CacheRepository,
 	client client.ExternalAPIClient,
+	history repository.HistoryRepository,
+	changes repository.ChangeRepository,
+	watches repository.WatchRepository,
+	pending repository.PendingQueueRepository,
+	pageGate client.PageGate,
 ) TrackingService {
-	batcherInstance := batcher.NewBatcher(config.BatcherConfig, cache, client)
-
-	return &trackingService{
-		batcher: batcherInstance,
+	s := &trackingService{
 		cache:   cache,
 		client:  client,
 		config:  config,
+		history: history,
+		changes: changes,
 		active:  false,
 	}
+
+	var opts []batcher.Option
+	if history != nil {
+		opts = append(opts, batcher.WithResultHook(s.recordHistory))
+	}
+	if changes != nil {
+		opts = append(opts, batcher.WithResultHook(s.detectChanges))
+	}
+	if pending != nil {
+		opts = append(opts, batcher.WithPendingQueue(pending))
+	}
+	if pageGate != nil {
+		opts = append(opts, batcher.WithPageGate(pageGate))
+	}
+	s.batcher = batcher.NewBatcher(config.BatcherConfig, cache, client, opts...)
+
+	if config.SchedulerConfig.Enabled && watches != nil {
+		s.scheduler = scheduler.New(config.SchedulerConfig, watches, s.batcher)
+	}
+
+	return s
 }
 
-func (s *trackingService) TrackPackage(ctx context.Context, trackCode string) <-chan models.TrackResponse {
+func (s *trackingService) TrackPackage(ctx context.Context, trackCode string, priority batcher.Priority) <-chan models.TrackResponse {
 	s.mu.RLock()
 	if !s.active {
 		s.mu.RUnlock()
@@ -62,7 +94,73 @@ func (s *trackingService) TrackPackage(ctx context.Context, trackCode string) <-
 	}
 
 	log.Printf("Adding track code to batch: %s", trackCode)
-	return s.batcher.AddRequest(ctx, trackCode)
+	return s.batcher.AddRequest(ctx, trackCode, priority)
+}
+
+func (s *trackingService) History(ctx context.Context, trackCode string) ([]models.HistoryEvent, error) {
+	if s.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	return s.history.GetHistory(ctx, trackCode)
+}
+
+func (s *trackingService) Changes(ctx context.Context, trackCode string, since time.Time) ([]models.TrackChange, error) {
+	if s.changes == nil {
+		return nil, ErrChangesDisabled
+	}
+
+	return s.changes.ListChanges(ctx, trackCode, since)
+}
+
+func (s *trackingService) Watch(ctx context.Context, trackCode string) (*models.Watch, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulerDisabled
+	}
+	if err := validateTrackCode(trackCode); err != nil {
+		return nil, err
+	}
+	return s.scheduler.Watch(ctx, trackCode)
+}
+
+func (s *trackingService) Unwatch(ctx context.Context, trackCode string) error {
+	if s.scheduler == nil {
+		return ErrSchedulerDisabled
+	}
+	if err := validateTrackCode(trackCode); err != nil {
+		return err
+	}
+	return s.scheduler.Unwatch(ctx, trackCode)
+}
+
+func (s *trackingService) GetWatch(ctx context.Context, trackCode string) (*models.Watch, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulerDisabled
+	}
+	if err := validateTrackCode(trackCode); err != nil {
+		return nil, err
+	}
+	return s.scheduler.Get(ctx, trackCode)
+}
+
+// validateTrackCode applies the check the provider clients use, so watches
+// never store or re-enqueue codes they would reject.
+func validateTrackCode(trackCode string) error {
+	if !client.IsValidTrackCode(trackCode) {
+		return erors.NewClientError("invalid tracking code format", erors.ErrInvalidTrackCode)
+	}
+	return nil
+}
+
+func (s *trackingService) recordHistory(ctx context.Context, trackCode string, data *models.TrackData) {
+	added, err := s.history.AddEvents(ctx, trackCode, data.Events, time.Now())
+	if err != nil {
+		log.Printf("service.recordHistory.Error: %s: %v", trackCode, err)
+		return
+	}
+	if added > 0 {
+		log.Printf("service.recordHistory: %d new events for %s", added, trackCode)
+	}
 }
 
 func (s *trackingService) Start(ctx context.Context) error {
@@ -77,6 +175,10 @@ func (s *trackingService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start batcher: %w", err)
 	}
 
+	if s.scheduler != nil {
+		s.scheduler.Start(ctx)
+	}
+
 	s.active = true
 	log.Println("Tracking service started successfully")
 
@@ -91,6 +193,10 @@ func (s *trackingService) Stop() error {
 		return fmt.Errorf("service is not running")
 	}
 
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+
 	if err := s.batcher.Stop(); err != nil {
 		return fmt.Errorf("failed to stop batcher: %w", err)
 	}
@@ -101,6 +207,30 @@ func (s *trackingService) Stop() error {
 	return nil
 }
 
+// Shutdown stops the scheduler and drains the batcher until ctx is done. New
+// requests are refused while draining.
+func (s *trackingService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return fmt.Errorf("service is not running")
+	}
+	s.active = false
+	s.mu.Unlock()
+
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+
+	if err := s.batcher.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to drain batcher: %w", err)
+	}
+
+	log.Println("Tracking service drained and stopped")
+
+	return nil
+}
+
 func (s *trackingService) Health(ctx context.Context) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -117,5 +247,53 @@ func (s *trackingService) Health(ctx context.Context) error {
 		return fmt.Errorf("cache health check failed: %w", err)
 	}
 
+	if s.history != nil {
+		if err := s.history.Health(ctx); err != nil {
+			return fmt.Errorf("history health check failed: %w", err)
+		}
+	}
+
 	return nil
 }
+
+func (s *trackingService) HealthDetails(ctx context.Context) HealthDetails {
+	var details HealthDetails
+
+	if reporter, ok := s.client.(client.StatsReporter); ok {
+		stats := reporter.ProviderStats()
+		details.Provider = &stats
+
+		if stats.Drift != nil && stats.Drift.Alarm {
+			details.Warnings = append(details.Warnings, fmt.Sprintf(
+				"layout drift suspected for %s: %.0f%% of recent parses were empty",
+				stats.Provider, stats.Drift.EmptyRatio*100))
+		}
+		if stats.BackoffUntil != nil {
+			details.Warnings = append(details.Warnings, fmt.Sprintf(
+				"%s is blocking requests, backing off until %s",
+				stats.Provider, stats.BackoffUntil.Format(time.RFC3339)))
+		}
+	}
+
+	stats := s.batcher.Stats()
+	details.Batcher = &stats
+	if stats.StuckWorkers > 0 {
+		details.Warnings = append(details.Warnings, fmt.Sprintf(
+			"%d of %d batcher workers stuck past the batch deadline",
+			stats.StuckWorkers, stats.Workers))
+	}
+	if starvation := s.config.BatcherConfig.StarvationTimeout; starvation > 0 &&
+		stats.OldestPendingSeconds > starvation.Seconds() {
+		details.Warnings = append(details.Warnings, fmt.Sprintf(
+			"batcher backlog: oldest request waiting %.0fs",
+			stats.OldestPendingSeconds))
+	}
+
+	if s.scheduler != nil {
+		if count, err := s.scheduler.Count(ctx); err == nil {
+			details.WatchedCodes = &count
+		}
+	}
+
+	return details
+}
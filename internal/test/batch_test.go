@@ -3,19 +3,33 @@ package batcher
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/shamil/proxy_track_service-1/internal/batcher"
+	"github.com/shamil/proxy_track_service-1/internal/clock"
 	"github.com/shamil/proxy_track_service-1/internal/config"
+	"github.com/shamil/proxy_track_service-1/internal/erors"
 	"github.com/shamil/proxy_track_service-1/internal/models"
 	"github.com/shamil/proxy_track_service-1/internal/repository"
 )
 
+// testPriority is the lane used by tests that do not exercise priorities.
+var testPriority = batcher.PriorityNormal
+
 type MockExternalAPIClient struct {
 	requests []string
 	mu       sync.Mutex
+
+	// hang makes lookups wait until their context is cancelled.
+	hang bool
+	// calls, when set, receives the codes of every lookup once recorded.
+	calls chan []string
+	// fail, when set, is returned by every lookup.
+	fail error
 }
 
 func NewMockExternalAPIClient() *MockExternalAPIClient {
@@ -26,9 +40,21 @@ func NewMockExternalAPIClient() *MockExternalAPIClient {
 
 func (m *MockExternalAPIClient) TrackPackagesBatch(ctx context.Context, trackCodes []string) (map[string]*models.TrackData, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	m.requests = append(m.requests, trackCodes...)
+	m.mu.Unlock()
+
+	if m.calls != nil {
+		m.calls <- trackCodes
+	}
+
+	if m.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if m.fail != nil {
+		return nil, m.fail
+	}
+
 	result := make(map[string]*models.TrackData)
 	for _, code := range trackCodes {
 		result[code] = &models.TrackData{
@@ -57,6 +83,10 @@ func (m *MockExternalAPIClient) Health(ctx context.Context) error {
 	return nil
 }
 
+func (m *MockExternalAPIClient) Close() error {
+	return nil
+}
+
 func (m *MockExternalAPIClient) GetRequestCount() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -71,15 +101,23 @@ func (m *MockExternalAPIClient) GetRequests() []string {
 
 type MockCacheRepository struct {
 	data map[string]*models.TrackData
+	ttls map[string]time.Duration
 	mu   sync.Mutex
 }
 
 func NewMockCacheRepository() *MockCacheRepository {
 	return &MockCacheRepository{
 		data: make(map[string]*models.TrackData),
+		ttls: make(map[string]time.Duration),
 	}
 }
 
+func (m *MockCacheRepository) TTL(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ttls[key]
+}
+
 func (m *MockCacheRepository) GetTrackData(ctx context.Context, trackCode string) (*models.TrackData, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -95,6 +133,7 @@ func (m *MockCacheRepository) SetTrackData(ctx context.Context, trackCode string
 	defer m.mu.Unlock()
 
 	m.data[trackCode] = data
+	m.ttls[trackCode] = ttl
 	return nil
 }
 
@@ -173,7 +212,7 @@ func TestBatcherBatchSize(t *testing.T) {
 		wg.Add(1)
 		go func(i int, code string) {
 			defer wg.Done()
-			responseChan := batcher.AddRequest(ctx, code)
+			responseChan := batcher.AddRequest(ctx, code, testPriority)
 			select {
 			case response := <-responseChan:
 				responses[i] = response
@@ -221,15 +260,16 @@ func TestBatcherBatchSize(t *testing.T) {
 // TestBatcherTimeout - тест таймаута батча
 func TestBatcherTimeout(t *testing.T) {
 	config := config.BatcherConfig{
-		BatchSize:    10,                     // Большой размер батча
-		BatchTimeout: 100 * time.Millisecond, // Короткий таймаут
+		BatchSize:    10, // Большой размер батча
+		BatchTimeout: 2 * time.Second,
 		Workers:      1,
 	}
 
 	mockClient := NewMockExternalAPIClient()
 	mockCache := NewMockCacheRepository()
+	fake := clock.NewFake(time.Now())
 
-	batcher := batcher.NewBatcher(config, mockCache, mockClient)
+	batcher := batcher.NewBatcher(config, mockCache, mockClient, batcher.WithClock(fake))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -241,31 +281,30 @@ func TestBatcherTimeout(t *testing.T) {
 	defer batcher.Stop()
 
 	trackCodes := []string{"TIMEOUT001", "TIMEOUT002"}
-
-	var wg sync.WaitGroup
-	responses := make([]models.TrackResponse, len(trackCodes))
-
+	responseChans := make([]<-chan models.TrackResponse, len(trackCodes))
 	for i, trackCode := range trackCodes {
-		wg.Add(1)
-		go func(i int, code string) {
-			defer wg.Done()
-			responseChan := batcher.AddRequest(ctx, code)
-			select {
-			case response := <-responseChan:
-				responses[i] = response
-			case <-ctx.Done():
-				t.Errorf("Request %d timed out", i)
-			}
-		}(i, trackCode)
+		responseChans[i] = batcher.AddRequest(ctx, trackCode, testPriority)
 	}
-	wg.Wait()
+	waitQueued(t, batcher, len(trackCodes))
+	fake.BlockUntil(1)
 
-	for i, response := range responses {
-		if !response.Status {
-			t.Errorf("Request %d failed: %s", i, response.Error)
-		}
-		if response.Data == nil {
-			t.Errorf("Request %d has no data", i)
+	fake.Advance(config.BatchTimeout - time.Millisecond)
+	if requestCount := mockClient.GetRequestCount(); requestCount != 0 {
+		t.Fatalf("Batch sent before the timeout: %d requests", requestCount)
+	}
+
+	fake.Advance(time.Millisecond)
+	for i, responseChan := range responseChans {
+		select {
+		case response := <-responseChan:
+			if !response.Status {
+				t.Errorf("Request %d failed: %s", i, response.Error)
+			}
+			if response.Data == nil {
+				t.Errorf("Request %d has no data", i)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Request %d timed out", i)
 		}
 	}
 
@@ -280,6 +319,23 @@ func TestBatcherTimeout(t *testing.T) {
 	}
 }
 
+// waitQueued waits until the main loop of b has picked up n requests. It only
+// yields to the loop and never reads the wall clock; a batcher that never
+// gets there fails the test by the go test timeout.
+func waitQueued(t *testing.T, b batcher.BatcherInterface, n int) {
+	t.Helper()
+	for {
+		stats := b.Stats()
+		if stats.Queued == n && stats.Incoming == 0 {
+			return
+		}
+		if stats.Queued > n {
+			t.Fatalf("Batcher stats %+v, want %d requests in the lanes", stats, n)
+		}
+		runtime.Gosched()
+	}
+}
+
 // TestBatcherMultipleBatches - тест нескольких батчей
 func TestBatcherMultipleBatches(t *testing.T) {
 	config := config.BatcherConfig{
@@ -311,7 +367,7 @@ func TestBatcherMultipleBatches(t *testing.T) {
 		wg.Add(1)
 		go func(i int, code string) {
 			defer wg.Done()
-			responseChan := batcher.AddRequest(ctx, code)
+			responseChan := batcher.AddRequest(ctx, code, testPriority)
 			select {
 			case response := <-responseChan:
 				responses[i] = response
@@ -378,7 +434,7 @@ func TestBatcherConcurrency(t *testing.T) {
 		wg.Add(1)
 		go func(i int, code string) {
 			defer wg.Done()
-			responseChan := batcher.AddRequest(ctx, code)
+			responseChan := batcher.AddRequest(ctx, code, testPriority)
 			select {
 			case response := <-responseChan:
 				responses[i] = response
@@ -408,3 +464,299 @@ func TestBatcherConcurrency(t *testing.T) {
 		t.Errorf("Expected %d track codes, got %d", numRequests, len(requests))
 	}
 }
+
+// TestBatcherInteractiveTimeout - интерактивный запрос не ждет общий таймаут батча
+func TestBatcherInteractiveTimeout(t *testing.T) {
+	config := config.BatcherConfig{
+		BatchSize:          10,
+		BatchTimeout:       2 * time.Second,
+		Workers:            1,
+		InteractiveTimeout: 50 * time.Millisecond,
+	}
+
+	mockClient := NewMockExternalAPIClient()
+	mockCache := NewMockCacheRepository()
+	fake := clock.NewFake(time.Now())
+
+	b := batcher.NewBatcher(config, mockCache, mockClient, batcher.WithClock(fake))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Failed to start batcher: %v", err)
+	}
+	defer b.Stop()
+
+	bulkChan := b.AddRequest(ctx, "BULK001", batcher.PriorityBulk)
+	interactiveChan := b.AddRequest(ctx, "INTERACTIVE001", batcher.PriorityInteractive)
+	waitQueued(t, b, 2)
+	fake.BlockUntil(1)
+
+	fake.Advance(config.InteractiveTimeout)
+	select {
+	case response := <-interactiveChan:
+		if !response.Status {
+			t.Fatalf("Interactive request failed: %s", response.Error)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Interactive request waited past %s", config.InteractiveTimeout)
+	}
+
+	// The bulk request fits into the same batch and rides along.
+	select {
+	case response := <-bulkChan:
+		if !response.Status {
+			t.Errorf("Bulk request failed: %s", response.Error)
+		}
+	case <-ctx.Done():
+		t.Errorf("Bulk request was not sent with the interactive batch")
+	}
+}
+
+// TestBatcherBackpressure - при заполненной очереди запрос ждет и получает Retry-After
+func TestBatcherBackpressure(t *testing.T) {
+	config := config.BatcherConfig{
+		BatchSize:      10,
+		BatchTimeout:   time.Second,
+		Workers:        1,
+		QueueCapacity:  1,
+		EnqueueTimeout: 50 * time.Millisecond,
+		RetryAfter:     3 * time.Second,
+	}
+
+	// The batcher is not started, so nothing drains the queue.
+	fake := clock.NewFake(time.Now())
+	b := batcher.NewBatcher(config, NewMockCacheRepository(), NewMockExternalAPIClient(), batcher.WithClock(fake))
+
+	ctx := context.Background()
+	queued := b.AddRequest(ctx, "QUEUED001", testPriority)
+
+	rejected := make(chan (<-chan models.TrackResponse), 1)
+	go func() { rejected <- b.AddRequest(ctx, "REJECTED001", testPriority) }()
+	fake.BlockUntil(1)
+
+	fake.Advance(config.EnqueueTimeout - time.Millisecond)
+	select {
+	case <-rejected:
+		t.Fatalf("Rejected before %s", config.EnqueueTimeout)
+	default:
+	}
+
+	fake.Advance(time.Millisecond)
+	response := <-<-rejected
+	if response.Status || response.Error != "service busy, try again later" {
+		t.Fatalf("Expected busy response, got %+v", response)
+	}
+	if response.RetryAfter != config.RetryAfter {
+		t.Errorf("RetryAfter = %s, want %s", response.RetryAfter, config.RetryAfter)
+	}
+
+	// Other lanes have their own queues.
+	select {
+	case response := <-b.AddRequest(ctx, "BULK001", batcher.PriorityBulk):
+		t.Errorf("Bulk request answered without a running batcher: %+v", response)
+	default:
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	response = <-b.AddRequest(cancelCtx, "CANCELLED001", testPriority)
+	if response.Error != "request cancelled" || response.RetryAfter != 0 {
+		t.Errorf("Expected cancelled response without hint, got %+v", response)
+	}
+
+	select {
+	case response := <-queued:
+		t.Errorf("Queued request answered without a running batcher: %+v", response)
+	default:
+	}
+}
+
+type MockPendingQueue struct {
+	mu       sync.Mutex
+	requests []models.PendingRequest
+}
+
+func (q *MockPendingQueue) Push(ctx context.Context, requests []models.PendingRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.requests = append(q.requests, requests...)
+	return nil
+}
+
+func (q *MockPendingQueue) PopAll(ctx context.Context) ([]models.PendingRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	requests := q.requests
+	q.requests = nil
+	return requests, nil
+}
+
+func (q *MockPendingQueue) Close() error {
+	return nil
+}
+
+// TestBatcherShutdownFlushes - при остановке ожидающий батч отправляется сразу
+func TestBatcherShutdownFlushes(t *testing.T) {
+	config := config.BatcherConfig{
+		BatchSize:    10,
+		BatchTimeout: 10 * time.Second,
+		Workers:      1,
+	}
+
+	// The fake clock never reaches BatchTimeout, so the batch can only go
+	// out because of the shutdown.
+	mockClient := NewMockExternalAPIClient()
+	fake := clock.NewFake(time.Now())
+	b := batcher.NewBatcher(config, NewMockCacheRepository(), mockClient, batcher.WithClock(fake))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Failed to start batcher: %v", err)
+	}
+
+	var responses []<-chan models.TrackResponse
+	for i := 0; i < 3; i++ {
+		responses = append(responses, b.AddRequest(ctx, fmt.Sprintf("DRAIN%03d", i), testPriority))
+	}
+	waitQueued(t, b, 3)
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer drainCancel()
+
+	if err := b.Shutdown(drainCtx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	for i, respChan := range responses {
+		if response := <-respChan; !response.Status {
+			t.Errorf("Request %d failed: %s", i, response.Error)
+		}
+	}
+
+	response := <-b.AddRequest(ctx, "LATE001", testPriority)
+	if response.Error != "service shutting down" || response.RetryAfter == 0 {
+		t.Errorf("Expected shutting down response with hint, got %+v", response)
+	}
+}
+
+// TestBatcherShutdownPersists - незавершенные запросы сохраняются и подхватываются после рестарта
+func TestBatcherShutdownPersists(t *testing.T) {
+	config := config.BatcherConfig{
+		BatchSize:    10,
+		BatchTimeout: 10 * time.Millisecond,
+		Workers:      1,
+	}
+
+	queue := &MockPendingQueue{}
+	hungClient := NewMockExternalAPIClient()
+	hungClient.hang = true
+	hungClient.calls = make(chan []string, 1)
+	fake := clock.NewFake(time.Now())
+	b := batcher.NewBatcher(config, NewMockCacheRepository(), hungClient,
+		batcher.WithPendingQueue(queue), batcher.WithClock(fake))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Failed to start batcher: %v", err)
+	}
+
+	respChan := b.AddRequest(ctx, "HUNG001", batcher.PriorityBulk)
+	waitQueued(t, b, 1)
+	fake.BlockUntil(1)
+	fake.Advance(config.BatchTimeout)
+	<-hungClient.calls
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer drainCancel()
+
+	if err := b.Shutdown(drainCtx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if response := <-respChan; response.Error != "service shutting down" {
+		t.Errorf("Expected shutting down response, got %+v", response)
+	}
+	if len(queue.requests) != 1 || queue.requests[0].TrackCode != "HUNG001" || queue.requests[0].Priority != "bulk" {
+		t.Fatalf("Pending queue = %+v, want HUNG001 in the bulk lane", queue.requests)
+	}
+
+	mockClient := NewMockExternalAPIClient()
+	mockClient.calls = make(chan []string, 1)
+	restartClock := clock.NewFake(time.Now())
+	restarted := batcher.NewBatcher(config, NewMockCacheRepository(), mockClient,
+		batcher.WithPendingQueue(queue), batcher.WithClock(restartClock))
+	if err := restarted.Start(ctx); err != nil {
+		t.Fatalf("Failed to start batcher: %v", err)
+	}
+	defer restarted.Stop()
+
+	waitQueued(t, restarted, 1)
+	restartClock.BlockUntil(1)
+	restartClock.Advance(config.BatchTimeout)
+	if requests := <-mockClient.calls; len(requests) != 1 || requests[0] != "HUNG001" {
+		t.Errorf("Recovered requests = %v, want [HUNG001]", requests)
+	}
+}
+
+// TestBatcherCacheTTL - найденные данные кешируются на BATCH_CACHE_TTL, по умолчанию на 5 минут
+func TestBatcherCacheTTL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ttl, want time.Duration
+	}{
+		"configured": {ttl: time.Hour, want: time.Hour},
+		"default":    {want: 5 * time.Minute},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := config.BatcherConfig{BatchSize: 1, BatchTimeout: time.Second, Workers: 1, CacheTTL: tc.ttl}
+			mockCache := NewMockCacheRepository()
+			b := batcher.NewBatcher(config, mockCache, NewMockExternalAPIClient(), batcher.WithClock(clock.NewFake(time.Now())))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := b.Start(ctx); err != nil {
+				t.Fatalf("Failed to start batcher: %v", err)
+			}
+			defer b.Stop()
+
+			if response := <-b.AddRequest(ctx, "CACHE001", testPriority); !response.Status {
+				t.Fatalf("Request failed: %s", response.Error)
+			}
+			if ttl := mockCache.TTL("CACHE001"); ttl != tc.want {
+				t.Errorf("Cache TTL = %s, want %s", ttl, tc.want)
+			}
+		})
+	}
+}
+
+// TestBatcherProviderBlocked - при блокировке провайдера клиент получает Retry-After из бэкоффа
+func TestBatcherProviderBlocked(t *testing.T) {
+	config := config.BatcherConfig{BatchSize: 1, BatchTimeout: time.Second, Workers: 1}
+
+	blockedErr := erors.NewProviderBlockedError("4px", erors.BlockChallenge, "captcha")
+	blockedErr.RetryAfter = 90 * time.Second
+	mockClient := NewMockExternalAPIClient()
+	mockClient.fail = erors.NewClientError("tracking provider blocked the request", blockedErr)
+	b := batcher.NewBatcher(config, NewMockCacheRepository(), mockClient, batcher.WithClock(clock.NewFake(time.Now())))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Failed to start batcher: %v", err)
+	}
+	defer b.Stop()
+
+	response := <-b.AddRequest(ctx, "BLOCKED001", testPriority)
+	if response.Status || !strings.Contains(response.Error, "tracking provider blocked the request") {
+		t.Fatalf("Expected blocked response, got %+v", response)
+	}
+	if response.RetryAfter != blockedErr.RetryAfter {
+		t.Errorf("RetryAfter = %s, want %s", response.RetryAfter, blockedErr.RetryAfter)
+	}
+}